@@ -5,6 +5,7 @@ import (
 	// "io"
 	"bytes"
 	"encoding/json"
+	"math"
 )
 
 // an AstNode
@@ -27,17 +28,25 @@ const (
 	EXPRESSION_STATEMENT
 	IF_STATEMENT
 	FOR_STATEMENT
-	// LABELED_STATEMENT
-	// BREAK_STATEMENT
-	// CONTINUE_STATEMENT
+	FOR_IN_STATEMENT
+	FOR_OF_STATEMENT
+	WHILE_STATEMENT
+	DO_WHILE_STATEMENT
+	LABELED_STATEMENT
+	BREAK_STATEMENT
+	CONTINUE_STATEMENT
 	// WITH_STATEMENT
-	// SWITCH_STATEMENT
+	SWITCH_STATEMENT
+	SWITCH_CASE
 	RETURN_STATEMENT
-	// THROW_STATEMENT
+	THROW_STATEMENT
+	TRY_STATEMENT
+	CATCH_CLAUSE
 	ASSIGNMENT_EXPRESSION
 	MEMBER_EXPRESSION
 	THIS_EXPRESSION
 	FUNCTION_EXPRESSION
+	ARROW_FUNCTION_EXPRESSION
 	CALL_EXPRESSION
 	VARIABLE_DECLARATION
 	VARIABLE_DECLARATOR
@@ -47,10 +56,69 @@ const (
 	UNARY_EXPRESSION
 	BINARY_EXPRESSION
 	UPDATE_EXPRESSION
+	LOGICAL_EXPRESSION
+	TEMPLATE_LITERAL
+	TEMPLATE_ELEMENT
+	TAGGED_TEMPLATE_EXPRESSION
+	CLASS_DECLARATION
+	CLASS_EXPRESSION
+	CLASS_BODY
+	METHOD_DEFINITION
+	PROPERTY_DEFINITION
+	PRIVATE_IDENTIFIER
+	STATIC_BLOCK
+	SUPER
+	OBJECT_PATTERN
+	ARRAY_PATTERN
+	ASSIGNMENT_PATTERN
+	REST_ELEMENT
+	SPREAD_ELEMENT
+	IMPORT_DECLARATION
+	IMPORT_SPECIFIER
+	IMPORT_DEFAULT_SPECIFIER
+	IMPORT_NAMESPACE_SPECIFIER
+	IMPORT_ATTRIBUTE
+	EXPORT_NAMED_DECLARATION
+	EXPORT_SPECIFIER
+	EXPORT_DEFAULT_DECLARATION
+	EXPORT_ALL_DECLARATION
+	IMPORT_EXPRESSION
+	META_PROPERTY
+	YIELD_EXPRESSION
+	AWAIT_EXPRESSION
+	CONDITIONAL_EXPRESSION
+	SEQUENCE_EXPRESSION
+	CHAIN_EXPRESSION
+	LINE_COMMENT
+	BLOCK_COMMENT
 )
 
 type AstNodeMeta struct {
-	Type  AstType `json:"type"`
+	Type  AstType         `json:"type"`
+	Range *[2]int         `json:"range,omitempty"`
+	Loc   *SourceLocation `json:"loc,omitempty"`
+	// comments around the node, set when the parser is asked to attach them
+	LeadingComments  []*Comment `json:"leadingComments,omitempty"`
+	TrailingComments []*Comment `json:"trailingComments,omitempty"`
+	InnerComments    []*Comment `json:"innerComments,omitempty"`
+}
+
+// a line or block comment, with the text between its delimiters as its value
+type Comment struct {
+	AstNodeMeta
+	Value string `json:"value"`
+}
+
+// source span of a node, set when the parser is asked for locations
+type SourceLocation struct {
+	Start Position `json:"start"`
+	End   Position `json:"end"`
+}
+
+// lines are counted from 1, as in ESTree
+type Position struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
 }
 
 type LiteralNull struct {
@@ -65,12 +133,37 @@ type LiteralString struct {
 	Raw   string  `json:"raw"`
 }
 
+type LiteralBoolean struct {
+	AstNodeMeta
+	Value bool    `json:"value"`
+	Raw   string  `json:"raw"`
+}
+
 type LiteralNumber struct {
 	AstNodeMeta
 	Value float64 `json:"value"`
 	Raw   string  `json:"raw"`
 }
 
+type LiteralBigInt struct {
+	AstNodeMeta
+	Value  interface{} `json:"value"`
+	Raw    string      `json:"raw"`
+	Bigint string      `json:"bigint"`
+}
+
+type LiteralRegExp struct {
+	AstNodeMeta
+	Value interface{} `json:"value"`
+	Raw   string      `json:"raw"`
+	Regex RegExp      `json:"regex"`
+}
+
+type RegExp struct {
+	Pattern string `json:"pattern"`
+	Flags   string `json:"flags"`
+}
+
 type Identifier struct {
 	AstNodeMeta
 	Name string  `json:"name"`
@@ -81,11 +174,18 @@ type Property struct {
 	Key   AstNode `json:"key"`
 	Value AstNode `json:"value"`
 	Kind  string  `json:"kind"`
+	// methods and shorthands are written without a colon
+	Method    bool `json:"method"`
+	Shorthand bool `json:"shorthand"`
+	Computed  bool `json:"computed"`
 }
 
+// source type is "script" or "module"
 type Program struct {
 	AstNodeMeta
-	Body []AstNode `json:"body"`
+	Body       []AstNode  `json:"body"`
+	SourceType string     `json:"sourceType"`
+	Comments   []*Comment `json:"comments,omitempty"`
 }
 
 type FunctionDeclaration struct {
@@ -97,6 +197,7 @@ type FunctionDeclaration struct {
 	Rest       AstNode   `json:"rest"`
 	Generator  bool      `json:"generator"`
 	Expression bool      `json:"expression"`
+	Async      bool      `json:"async"`
 	Source     string 	 `json:"-"`
 }
 
@@ -129,11 +230,86 @@ type ForStatement struct {
 	Body AstNode `json:"body"`
 }
 
+type ForInStatement struct {
+	AstNodeMeta
+	Left  AstNode `json:"left"`
+	Right AstNode `json:"right"`
+	Body  AstNode `json:"body"`
+}
+
+type ForOfStatement struct {
+	AstNodeMeta
+	Left  AstNode `json:"left"`
+	Right AstNode `json:"right"`
+	Body  AstNode `json:"body"`
+	Await bool    `json:"await"`
+}
+
+type WhileStatement struct {
+	AstNodeMeta
+	Test AstNode `json:"test"`
+	Body AstNode `json:"body"`
+}
+
+type DoWhileStatement struct {
+	AstNodeMeta
+	Body AstNode `json:"body"`
+	Test AstNode `json:"test"`
+}
+
+type LabeledStatement struct {
+	AstNodeMeta
+	Label AstNode `json:"label"`
+	Body  AstNode `json:"body"`
+}
+
+type BreakStatement struct {
+	AstNodeMeta
+	Label AstNode `json:"label"`
+}
+
+type ContinueStatement struct {
+	AstNodeMeta
+	Label AstNode `json:"label"`
+}
+
+type SwitchStatement struct {
+	AstNodeMeta
+	Discriminant AstNode   `json:"discriminant"`
+	Cases        []AstNode `json:"cases"`
+}
+
+// test is null for the default case
+type SwitchCase struct {
+	AstNodeMeta
+	Test       AstNode   `json:"test"`
+	Consequent []AstNode `json:"consequent"`
+}
+
 type ReturnStatement struct {
 	AstNodeMeta
 	Argument AstNode `json:"argument"`
 }
 
+type ThrowStatement struct {
+	AstNodeMeta
+	Argument AstNode `json:"argument"`
+}
+
+type TryStatement struct {
+	AstNodeMeta
+	Block     AstNode `json:"block"`
+	Handler   AstNode `json:"handler"`
+	Finalizer AstNode `json:"finalizer"`
+}
+
+// param is null for an optional catch binding
+type CatchClause struct {
+	AstNodeMeta
+	Param AstNode `json:"param"`
+	Body  AstNode `json:"body"`
+}
+
 type AssignmentExpression struct {
 	AstNodeMeta
 	Operator string  `json:"operator"`
@@ -146,6 +322,7 @@ type MemberExpression struct {
 	Computed bool    `json:"computed"`
 	Object   AstNode `json:"object"`
 	Property AstNode `json:"property"`
+	Optional bool    `json:"optional"`
 }
 
 type ThisExpression struct {
@@ -161,13 +338,29 @@ type FunctionExpression struct {
 	Rest       AstNode   `json:"rest"`
 	Generator  bool      `json:"generator"`
 	Expression bool      `json:"expression"`
+	Async      bool      `json:"async"`
 	Source     string 	 `json:"-"`
 }
 
+// expression is set when the body is an expression rather than a block
+type ArrowFunctionExpression struct {
+	AstNodeMeta
+	Id         AstNode   `json:"id"`
+	Params     []AstNode `json:"params"`
+	Defaults   []AstNode `json:"defaults"`
+	Body       AstNode   `json:"body"`
+	Rest       AstNode   `json:"rest"`
+	Generator  bool      `json:"generator"`
+	Expression bool      `json:"expression"`
+	Async      bool      `json:"async"`
+	Source     string    `json:"-"`
+}
+
 type CallExpression struct {
 	AstNodeMeta
 	Callee    AstNode   `json:"callee"`
 	Arguments []AstNode `json:"arguments"`
+	Optional  bool      `json:"optional"`
 }
 
 type VariableDeclaration struct {
@@ -219,10 +412,251 @@ type UpdateExpression struct {
 	Prefix bool `json:"prefix"`
 }
 
+type LogicalExpression struct {
+	AstNodeMeta
+	Operator string  `json:"operator"`
+	Left     AstNode `json:"left"`
+	Right    AstNode `json:"right"`
+}
+
+type ConditionalExpression struct {
+	AstNodeMeta
+	Test       AstNode `json:"test"`
+	Consequent AstNode `json:"consequent"`
+	Alternate  AstNode `json:"alternate"`
+}
+
+// expressions separated by commas, evaluating to the last
+type SequenceExpression struct {
+	AstNodeMeta
+	Expressions []AstNode `json:"expressions"`
+}
+
+// member accesses and calls containing an optional link such as a?.b
+type ChainExpression struct {
+	AstNodeMeta
+	Expression AstNode `json:"expression"`
+}
+
+type TemplateLiteral struct {
+	AstNodeMeta
+	Quasis      []AstNode `json:"quasis"`
+	Expressions []AstNode `json:"expressions"`
+}
+
+type TemplateElement struct {
+	AstNodeMeta
+	Value TemplateValue `json:"value"`
+	Tail  bool          `json:"tail"`
+}
+
+// cooked is null for invalid escapes in tagged templates
+type TemplateValue struct {
+	Cooked interface{} `json:"cooked"`
+	Raw    string      `json:"raw"`
+}
+
+type TaggedTemplateExpression struct {
+	AstNodeMeta
+	Tag   AstNode `json:"tag"`
+	Quasi AstNode `json:"quasi"`
+}
+
+type ClassDeclaration struct {
+	AstNodeMeta
+	Id         AstNode `json:"id"`
+	SuperClass AstNode `json:"superClass"`
+	Body       AstNode `json:"body"`
+}
+
+type ClassExpression struct {
+	AstNodeMeta
+	Id         AstNode `json:"id"`
+	SuperClass AstNode `json:"superClass"`
+	Body       AstNode `json:"body"`
+}
+
+type ClassBody struct {
+	AstNodeMeta
+	Body []AstNode `json:"body"`
+}
+
+// kind is constructor, method, get or set
+type MethodDefinition struct {
+	AstNodeMeta
+	Key      AstNode `json:"key"`
+	Computed bool    `json:"computed"`
+	Value    AstNode `json:"value"`
+	Kind     string  `json:"kind"`
+	Static   bool    `json:"static"`
+}
+
+// value is null for a field without an initializer
+type PropertyDefinition struct {
+	AstNodeMeta
+	Key      AstNode `json:"key"`
+	Computed bool    `json:"computed"`
+	Value    AstNode `json:"value"`
+	Static   bool    `json:"static"`
+}
+
+// name excludes the leading #
+type PrivateIdentifier struct {
+	AstNodeMeta
+	Name string `json:"name"`
+}
+
+type StaticBlock struct {
+	AstNodeMeta
+	Body []AstNode `json:"body"`
+}
+
+type Super struct {
+	AstNodeMeta
+}
+
+// properties are Property nodes with pattern values, and a final RestElement
+type ObjectPattern struct {
+	AstNodeMeta
+	Properties []AstNode `json:"properties"`
+}
+
+// skipped elements are null
+type ArrayPattern struct {
+	AstNodeMeta
+	Elements []AstNode `json:"elements"`
+}
+
+// a pattern with a default value
+type AssignmentPattern struct {
+	AstNodeMeta
+	Left  AstNode `json:"left"`
+	Right AstNode `json:"right"`
+}
+
+type RestElement struct {
+	AstNodeMeta
+	Argument AstNode `json:"argument"`
+}
+
+type SpreadElement struct {
+	AstNodeMeta
+	Argument AstNode `json:"argument"`
+}
+
+// a declaration without specifiers only runs the imported module
+type ImportDeclaration struct {
+	AstNodeMeta
+	Specifiers []AstNode `json:"specifiers"`
+	Source     AstNode   `json:"source"`
+	Attributes []AstNode `json:"attributes"`
+}
+
+// imported is an Identifier, or a string Literal naming the export
+type ImportSpecifier struct {
+	AstNodeMeta
+	Imported AstNode `json:"imported"`
+	Local    AstNode `json:"local"`
+}
+
+type ImportDefaultSpecifier struct {
+	AstNodeMeta
+	Local AstNode `json:"local"`
+}
+
+type ImportNamespaceSpecifier struct {
+	AstNodeMeta
+	Local AstNode `json:"local"`
+}
+
+// a with clause entry, such as type: "json"
+type ImportAttribute struct {
+	AstNodeMeta
+	Key   AstNode `json:"key"`
+	Value AstNode `json:"value"`
+}
+
+// either exports a declaration, or lists specifiers, re-exported when source is set
+type ExportNamedDeclaration struct {
+	AstNodeMeta
+	Declaration AstNode   `json:"declaration"`
+	Specifiers  []AstNode `json:"specifiers"`
+	Source      AstNode   `json:"source"`
+	Attributes  []AstNode `json:"attributes"`
+}
+
+// local and exported are Identifiers, or string Literals where a name is allowed to be one
+type ExportSpecifier struct {
+	AstNodeMeta
+	Local    AstNode `json:"local"`
+	Exported AstNode `json:"exported"`
+}
+
+// declaration is a function or class declaration, whose id may be null, or an expression
+type ExportDefaultDeclaration struct {
+	AstNodeMeta
+	Declaration AstNode `json:"declaration"`
+}
+
+// exported is null unless the module namespace is exported under a name
+type ExportAllDeclaration struct {
+	AstNodeMeta
+	Exported   AstNode   `json:"exported"`
+	Source     AstNode   `json:"source"`
+	Attributes []AstNode `json:"attributes"`
+}
+
+// a dynamic import(), with null options unless a second argument is given
+type ImportExpression struct {
+	AstNodeMeta
+	Source  AstNode `json:"source"`
+	Options AstNode `json:"options"`
+}
+
+// argument is null for a bare yield, and delegate is set for yield*
+type YieldExpression struct {
+	AstNodeMeta
+	Argument AstNode `json:"argument"`
+	Delegate bool    `json:"delegate"`
+}
+
+type AwaitExpression struct {
+	AstNodeMeta
+	Argument AstNode `json:"argument"`
+}
+
+// import.meta, where the keyword is the meta identifier
+type MetaProperty struct {
+	AstNodeMeta
+	Keyword  AstNode `json:"meta"`
+	Property AstNode `json:"property"`
+}
+
 func (self AstNodeMeta) AstType() AstType {
 	return self.Type
 }
 
+// metadata shared by all nodes, for reading or setting locations of any node
+func (self *AstNodeMeta) Meta() *AstNodeMeta {
+	return self
+}
+
+func (self Cursor) Position() Position {
+	return Position{self.Line + 1, self.Column}
+}
+
+// non-finite numbers have no json representation, so are written as null like JSON.stringify
+func (self LiteralNumber) MarshalJSON() ([]byte, error) {
+	type literalNumber LiteralNumber
+	if math.IsInf(self.Value, 0) || math.IsNaN(self.Value) {
+		return json.Marshal(struct {
+			literalNumber
+			Value interface{} `json:"value"`
+		}{literalNumber(self), nil})
+	}
+	return json.Marshal(literalNumber(self))
+}
+
 func FormattedAstBuffer(ast AstNode) (*bytes.Buffer, error) {
 	jsonStr, err := json.Marshal(ast)
 	if err != nil {
@@ -276,20 +710,34 @@ func (self AstType) String() string {
 		return "IfStatement"
 	case FOR_STATEMENT:
 		return "ForStatement"
-	// case LABELED_STATEMENT:
-	// 	return "LabeledStatement"
-	// case BREAK_STATEMENT:
-	// 	return "BreakStatement"
-	// case CONTINUE_STATEMENT:
-	// 	return "ContinueStatement"
+	case FOR_IN_STATEMENT:
+		return "ForInStatement"
+	case FOR_OF_STATEMENT:
+		return "ForOfStatement"
+	case WHILE_STATEMENT:
+		return "WhileStatement"
+	case DO_WHILE_STATEMENT:
+		return "DoWhileStatement"
+	case LABELED_STATEMENT:
+		return "LabeledStatement"
+	case BREAK_STATEMENT:
+		return "BreakStatement"
+	case CONTINUE_STATEMENT:
+		return "ContinueStatement"
 	// case WITH_STATEMENT:
 	// 	return "WithStatement"
-	// case SWITCH_STATEMENT:
-	// 	return "SwitchStatement"
+	case SWITCH_STATEMENT:
+		return "SwitchStatement"
+	case SWITCH_CASE:
+		return "SwitchCase"
 	case RETURN_STATEMENT:
 		return "ReturnStatement"
-	// case THROW_STATEMENT:
-	// 	return "ThrowStatement"
+	case THROW_STATEMENT:
+		return "ThrowStatement"
+	case TRY_STATEMENT:
+		return "TryStatement"
+	case CATCH_CLAUSE:
+		return "CatchClause"
 	case ASSIGNMENT_EXPRESSION:
 		return "AssignmentExpression"
 	case MEMBER_EXPRESSION:
@@ -298,6 +746,8 @@ func (self AstType) String() string {
 		return "ThisExpression"
 	case FUNCTION_EXPRESSION:
 		return "FunctionExpression"
+	case ARROW_FUNCTION_EXPRESSION:
+		return "ArrowFunctionExpression"
 	case CALL_EXPRESSION:
 		return "CallExpression"
 	case VARIABLE_DECLARATION:
@@ -316,6 +766,76 @@ func (self AstType) String() string {
 		return "BinaryExpression"
 	case UPDATE_EXPRESSION:
 		return "UpdateExpression"
+	case LOGICAL_EXPRESSION:
+		return "LogicalExpression"
+	case TEMPLATE_LITERAL:
+		return "TemplateLiteral"
+	case TEMPLATE_ELEMENT:
+		return "TemplateElement"
+	case TAGGED_TEMPLATE_EXPRESSION:
+		return "TaggedTemplateExpression"
+	case CLASS_DECLARATION:
+		return "ClassDeclaration"
+	case CLASS_EXPRESSION:
+		return "ClassExpression"
+	case CLASS_BODY:
+		return "ClassBody"
+	case METHOD_DEFINITION:
+		return "MethodDefinition"
+	case PROPERTY_DEFINITION:
+		return "PropertyDefinition"
+	case PRIVATE_IDENTIFIER:
+		return "PrivateIdentifier"
+	case STATIC_BLOCK:
+		return "StaticBlock"
+	case SUPER:
+		return "Super"
+	case OBJECT_PATTERN:
+		return "ObjectPattern"
+	case ARRAY_PATTERN:
+		return "ArrayPattern"
+	case ASSIGNMENT_PATTERN:
+		return "AssignmentPattern"
+	case REST_ELEMENT:
+		return "RestElement"
+	case SPREAD_ELEMENT:
+		return "SpreadElement"
+	case IMPORT_DECLARATION:
+		return "ImportDeclaration"
+	case IMPORT_SPECIFIER:
+		return "ImportSpecifier"
+	case IMPORT_DEFAULT_SPECIFIER:
+		return "ImportDefaultSpecifier"
+	case IMPORT_NAMESPACE_SPECIFIER:
+		return "ImportNamespaceSpecifier"
+	case IMPORT_ATTRIBUTE:
+		return "ImportAttribute"
+	case EXPORT_NAMED_DECLARATION:
+		return "ExportNamedDeclaration"
+	case EXPORT_SPECIFIER:
+		return "ExportSpecifier"
+	case EXPORT_DEFAULT_DECLARATION:
+		return "ExportDefaultDeclaration"
+	case EXPORT_ALL_DECLARATION:
+		return "ExportAllDeclaration"
+	case IMPORT_EXPRESSION:
+		return "ImportExpression"
+	case META_PROPERTY:
+		return "MetaProperty"
+	case YIELD_EXPRESSION:
+		return "YieldExpression"
+	case AWAIT_EXPRESSION:
+		return "AwaitExpression"
+	case CONDITIONAL_EXPRESSION:
+		return "ConditionalExpression"
+	case SEQUENCE_EXPRESSION:
+		return "SequenceExpression"
+	case CHAIN_EXPRESSION:
+		return "ChainExpression"
+	case LINE_COMMENT:
+		return "Line"
+	case BLOCK_COMMENT:
+		return "Block"
 
 	}
 	return "<#error: bad value>"
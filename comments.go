@@ -0,0 +1,156 @@
+package jaess
+
+import (
+	"strings"
+)
+
+// a finished node that may still pass its leading comments on to an enclosing node
+type _CommentTarget struct {
+	meta  *AstNodeMeta
+	start int
+}
+
+// records a comment read by the parser, to be collected or attached
+func (self *Parser) addComment(token *Token) {
+	node := new(Comment)
+	node.Type = LINE_COMMENT
+	block, value := _CommentText(token.Value)
+	if block {
+		node.Type = BLOCK_COMMENT
+	}
+	node.Value = value
+	end := token.End()
+	self.setLocation(&node.AstNodeMeta, token.Location, end)
+	self.comments = append(self.comments, node)
+
+	if self.AttachComment {
+		// attaching compares offsets, so comments always have a range
+		node.Range = &[2]int{token.Location.Offset, end.Offset}
+		self.leading = append(self.leading, node)
+		self.trailing = append(self.trailing, node)
+	}
+}
+
+// whether a comment is a block comment, and the text between its delimiters
+func _CommentText(raw string) (bool, string) {
+	if strings.HasPrefix(raw, "/*") {
+		return true, strings.TrimSuffix(strings.TrimPrefix(raw, "/*"), "*/")
+	}
+	return false, strings.TrimPrefix(raw, "//")
+}
+
+// attaches the comments read so far around a node spanning start to end,
+// which are passed on from inner nodes to the outermost node they surround
+func (self *Parser) attachComments(node AstNode, meta *AstNodeMeta, start int, end int) {
+	// comments up to the next token follow the node
+	_, _ = self.peekToken()
+
+	if program, ok := node.(*Program); ok && len(program.Body) > 0 {
+		return
+	}
+	if block, ok := node.(*BlockStatement); ok && len(block.Body) == 0 {
+		meta.InnerComments = self.innerComments(end)
+	}
+	trailing := self.trailingComments(end)
+	leading := self.leadingComments(start)
+	if len(leading) > 0 {
+		meta.LeadingComments = leading
+	}
+	if len(trailing) > 0 {
+		meta.TrailingComments = trailing
+	}
+	self.commentTargets = append(self.commentTargets, _CommentTarget{meta, start})
+}
+
+// takes the comments inside an empty block ending at end
+func (self *Parser) innerComments(end int) []*Comment {
+	inner := []*Comment{}
+	leading := []*Comment{}
+	for _, comment := range self.leading {
+		if comment.Range[0] <= end {
+			inner = append(inner, comment)
+		} else {
+			leading = append(leading, comment)
+		}
+	}
+	self.leading = leading
+	if len(inner) == 0 {
+		return nil
+	}
+
+	// inner comments follow nothing
+	trailing := []*Comment{}
+	for _, comment := range self.trailing {
+		if comment.Range[0] > end {
+			trailing = append(trailing, comment)
+		}
+	}
+	self.trailing = trailing
+	return inner
+}
+
+// takes the comments following a node ending at end, from the comments read since
+// the last node, or else from the node before it
+func (self *Parser) trailingComments(end int) []*Comment {
+	trailing := []*Comment{}
+	if len(self.trailing) > 0 {
+		for _, comment := range self.trailing {
+			if comment.Range[0] >= end {
+				trailing = append(trailing, comment)
+			}
+		}
+		self.trailing = self.trailing[:0]
+		return trailing
+	}
+
+	if len(self.commentTargets) > 0 {
+		last := self.commentTargets[len(self.commentTargets)-1].meta
+		if len(last.TrailingComments) > 0 && last.TrailingComments[0].Range[0] >= end {
+			trailing = last.TrailingComments
+			last.TrailingComments = nil
+		}
+	}
+	return trailing
+}
+
+// takes the comments preceding a node starting at start, from the inner nodes
+// it encloses, or else from the comments read since the last node
+func (self *Parser) leadingComments(start int) []*Comment {
+	leading := []*Comment{}
+	var target *AstNodeMeta
+	for len(self.commentTargets) > 0 {
+		last := self.commentTargets[len(self.commentTargets)-1]
+		if last.start < start {
+			break
+		}
+		target = last.meta
+		self.commentTargets = self.commentTargets[:len(self.commentTargets)-1]
+	}
+
+	if target != nil {
+		kept := []*Comment{}
+		for _, comment := range target.LeadingComments {
+			if comment.Range[1] <= start {
+				leading = append(leading, comment)
+			} else {
+				kept = append(kept, comment)
+			}
+		}
+		target.LeadingComments = nil
+		if len(kept) > 0 {
+			target.LeadingComments = kept
+		}
+		return leading
+	}
+
+	kept := []*Comment{}
+	for _, comment := range self.leading {
+		if comment.Range[0] <= start {
+			leading = append(leading, comment)
+		} else {
+			kept = append(kept, comment)
+		}
+	}
+	self.leading = kept
+	return leading
+}
@@ -36,7 +36,7 @@ func NewParseError(message string, args ...interface{}) *ParseError {
 	return err
 }
 
-func (self ParseError) SetLocation(location Cursor) ParseError {
+func (self *ParseError) SetLocation(location Cursor) *ParseError {
 	self.Location = location
 	return self
 }
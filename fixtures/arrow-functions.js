@@ -0,0 +1,12 @@
+var double = x => x * 2;
+var add = (a, b) => {
+  return a + b;
+};
+var empty = () => ({});
+var curried = a => b => a + b;
+items.map((item, index,) => item[index]);
+var load = async url => fetch(url);
+var save = async (key, value) => store(key, value);
+async(key);
+var inside = (x => x in object);
+var grouped = (a);
@@ -0,0 +1,45 @@
+var a = 1
+var b = 2
+a
+b
+x
+++y
+x
+++
+y
+c = d
+(e)
+f = g
+[h]
+i = j
+`k`
+l = m
+?.n
+function r() {
+  return
+  a + b
+}
+for (;;) {
+  break
+  continue
+}
+outer: for (;;) {
+  continue
+  outer
+}
+if (a) b
+else c
+do a; while (b) c
+do a
+while (b)
+var re = a
+/b/g
+let
+z = 1
+x = a /* multi
+line */ b
+throw new Error("x")
+var list = [1, 2]
+;[1, 2].forEach(f)
+a
+;
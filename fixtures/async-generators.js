@@ -0,0 +1,45 @@
+function* range(start, end) {
+  for (let i = start; i < end; i++) {
+    yield i;
+  }
+  yield;
+  return yield* tail();
+}
+
+async function load(url) {
+  const response = await fetch(url);
+  return await response.json();
+}
+
+async function* stream(source) {
+  for await (const chunk of source) {
+    yield await decode(chunk);
+  }
+}
+
+var loader = async function (items) {
+  await Promise.all(items.map(async item => await load(item)));
+};
+
+var counter = function* count() {
+  var sent = yield 1;
+  yield sent + (yield 2);
+};
+
+var helpers = {
+  async fetch() {
+    return await get();
+  },
+  *keys() {
+    yield "a";
+  }
+};
+
+class Queue {
+  async *drain() {
+    yield* this.items;
+  }
+}
+
+var await = 1, yield = 2;
+var legacy = await + yield;
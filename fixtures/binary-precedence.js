@@ -0,0 +1,11 @@
+a * b + c;
+a + b * c % d;
+a < b && c;
+x === y || z;
+a || b && c | d ^ e & f;
+a << 1 + 2 >= b;
+k in o instanceof Object;
+a ** b ** c;
+a - b - c;
+x ?? y;
+-a * !b;
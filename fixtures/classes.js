@@ -0,0 +1,53 @@
+// Shape - superclass
+class Shape {
+  x = -1;
+  y = 5;
+  #moves = 0;
+  static created = 0;
+  static #instances;
+
+  static {
+    Shape.#instances = [];
+  }
+
+  constructor() {
+    Shape.created++;
+    Shape.#instances.push(this);
+  }
+
+  get moves() {
+    return this.#moves;
+  }
+
+  set moves(value) {
+    this.#moves = value;
+  }
+
+  move(x, y) {
+    this.x += x;
+    this.y += y;
+    this.#moves++;
+    console.info("Shape moved.");
+  }
+
+  static ['from' + 'Point'](point) {
+    return new Shape();
+  }
+}
+
+// Rectangle - subclass
+class Rectangle extends Shape {
+  constructor() {
+    super(); //call super constructor.
+  }
+
+  move(x, y) {
+    super.move(x, y);
+  }
+}
+
+var Square = class Square extends Rectangle {};
+var rect = new Rectangle();
+
+assert(rect instanceof Rectangle);
+assert(rect instanceof Shape);
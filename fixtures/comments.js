@@ -0,0 +1,24 @@
+// leading line
+/**
+ * Adds two numbers.
+ */
+function add(a, b) {
+  return a + b; // sum
+}
+
+var x = 1; /* after x */
+/* before y */ var y = 2;
+
+function empty() {
+  // nothing here
+}
+
+class Shape {
+  /** The area. */
+  area() {
+    return /* none */ 0;
+  }
+}
+
+call(a, /* inline */ b);
+// at the end
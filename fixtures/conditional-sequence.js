@@ -0,0 +1,34 @@
+var sign = n < 0 ? -1 : n > 0 ? 1 : 0;
+var size = small ? big ? 2 : 1 : 0;
+x = a ? b = 1 : c = 2;
+for (var i = a ? "b" in c : d; i < 10; i++, j--) ;
+for (i = 0, j = 10; i < j; i += 1, j -= 1) ;
+a = 1, b = 2, c;
+x = (a, b, c);
+f((a, b), c);
+function first() {
+  return a, b;
+}
+async function wait() {
+  await (a, b);
+}
+a = b = c;
+a += 1;
+a -= 1;
+a *= 2;
+a /= 2;
+a %= 2;
+a **= 2;
+a <<= 1;
+a >>= 1;
+a >>>= 1;
+a &= 1;
+a |= 1;
+a ^= 1;
+a &&= b;
+a ||= b;
+a ??= b;
+o.count += 1;
+o[key] ||= [];
+++o.count;
+o[key]--;
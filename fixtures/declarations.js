@@ -0,0 +1,29 @@
+var a, b = 1, c;
+let d;
+let e = 2, f = e + 1;
+const g = 3, h = "four";
+for (let i = 0, n = 10; i < n; i++) {
+  let e = i;
+}
+{
+  let d = 5;
+  const g = d;
+}
+let = 6;
+let(7);
+switch (a) {
+  case 1:
+    let x = 1;
+    break;
+  default:
+    var y = 2;
+}
+try {
+  risky();
+} catch (err) {
+  var err = null;
+}
+function scoped(p) {
+  var p;
+  let q = p;
+}
@@ -0,0 +1,13 @@
+for (var key in object) {
+  visit(key);
+}
+for (let item of items) total += item;
+for (const name of names) {
+  continue;
+}
+for (node.next in graph) ;
+for (var legacy = 0 in object) ;
+for (x of [a, b]) {}
+for (var i = ("length" in list); i < 3; i++) ;
+for (f(a in b); ;) break;
+for (let in object) ;
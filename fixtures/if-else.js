@@ -0,0 +1,16 @@
+if (a) b(); else if (c) d(); else { e(); }
+if (x) {
+  y = 1;
+} else {
+  y = 2;
+}
+if (p) if (q) r(); else s();
+if (ok)
+  done();
+for (;;) x++;
+for (i = 0; i < n; i++) total += i;
+for (var j = 0; ; ) break;
+for (; k; ) {
+  k--;
+}
+while (m) if (m > 1) m--; else break;
@@ -0,0 +1,5 @@
+function plain(a, b) {}
+function defaults(a, b = 2, c) {}
+function rest(a, ...others) {}
+var arrow = (x = 1, ...ys) => ys;
+var method = function ([first], {second} = {}) {};
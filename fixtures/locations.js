@@ -0,0 +1,9 @@
+// locations are reported for every node
+function area(shape) {
+  return shape.width * (shape.height + 1);
+}
+
+var label = `size: ${area({width: 2, height: "😀"})}`;
+if (label) {
+  tags[0] = new Tag(label, -1);
+}
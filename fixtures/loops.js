@@ -0,0 +1,26 @@
+while (i < 10) {
+  i++;
+}
+while (queue.length) queue.pop();
+do {
+  n--;
+} while (n > 0)
+do x++; while (x < 5);
+outer: for (var i = 0; i < 3; i++) {
+  inner: while (true) {
+    if (i) {
+      continue outer;
+    }
+    break inner;
+  }
+}
+block: {
+  break block;
+}
+a: b: while (c) {
+  continue a;
+}
+while (d) {
+  break
+  label;
+}
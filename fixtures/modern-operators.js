@@ -0,0 +1,25 @@
+a?.b;
+a?.[key];
+a?.();
+a?.b.c(d)[e];
+a.b?.c?.(d);
+(a?.b).c;
+new (a?.b)();
+delete a?.b;
+x = a ?? b;
+x = a ?? b ?? c;
+x = (a || b) ?? c;
+x = a ?? (b && c);
+x = a ?? b | c;
+x = a || b && c;
+x = a ** b;
+x = a ** b ** c;
+x = (-a) ** b;
+x = a ** -b;
+x = ++a ** 2;
+x = a++ ** 2;
+x = -(a ** b);
+async function f() {
+  return (await a) ** 2;
+}
+x ??= a?.b ?? c;
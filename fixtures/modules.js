@@ -0,0 +1,27 @@
+import "./polyfills.js";
+import shapes from "./shapes.js";
+import * as geometry from "./geometry.js";
+import { area, perimeter as edge, "side length" as side, default as base } from "./measure.js";
+import defaults, { scale } from "./scale.js";
+import config from "./config.json" with { type: "json" };
+
+export var count = 1, { width, height } = config;
+export let ratio = width / height;
+export const unit = "px";
+export function resize(factor) {
+  return scale(factor);
+}
+export class Square {
+}
+export { area, edge as perimeter, side as "side length" };
+export { rotate, flip as mirror } from "./transform.js";
+export * from "./colors.js";
+export * as units from "./units.js" with { type: "json" };
+
+export default function () {
+  return import("./lazy.js", { with: { type: "json" } }).then(load);
+}
+
+function load(module) {
+  return [import.meta.url, module, shapes, geometry, base, defaults];
+}
@@ -0,0 +1,24 @@
+0;
+42;
+3.14;
+.5;
+5.;
+1e-9;
+2.5E+3;
+1_000_000;
+0.000_001;
+0xFF;
+0XdeadBEEF;
+0o17;
+0O777;
+0b1010;
+0B1_0;
+017;
+019;
+08.5;
+1e400;
+0x10000000000000001;
+10n;
+0xFFn;
+0b1_0n;
+1..toString();
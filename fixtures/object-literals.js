@@ -0,0 +1,39 @@
+var name = "shape", size = 2;
+
+var shape = {
+  name,
+  size,
+  if: true,
+  default: null,
+  "quoted key": 1,
+  3: "three",
+  [name + "Id"]: 7,
+  area() {
+    return this.size * this.size;
+  },
+  get side() {
+    return this.size;
+  },
+  set side(value) {
+    this.size = value;
+  },
+  *points() {
+    return [];
+  },
+  async load() {
+    return this;
+  },
+  async *stream() {
+  },
+  ["compute" + "d"]() {
+    return super.toString();
+  },
+  get: 1,
+  set() {
+    return 2;
+  },
+  async: 3
+};
+
+var { a, [name]: b = 1, ...others } = shape;
+({ a = 1, b: { c = 2 } } = shape);
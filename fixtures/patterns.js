@@ -0,0 +1,11 @@
+var [a, , b = 1, ...rest] = list;
+let {x, y: [z], 'w': {v = 2}, ...others} = point;
+const [[deep]] = nested;
+function f([first, second], {name}) { return first; }
+try {} catch ({message}) {}
+for (const [key, value] of entries) ;
+for ([a, b] of pairs) ;
+for ({a: b.c} in o) ;
+[a, b] = [b, a];
+({a: obj.prop, b: [c = 1]} = source);
+var g = ([p, q], {r: s}) => p;
@@ -0,0 +1,17 @@
+a=-1;
+x+-y;
+x-+y;
+b=!!a;
+c=a?.5:1;
+e={a:-1,b:!c};
+f=x=>-x;
+g=(...r)=>!r;
+a>>>=1;
+a>>=-1;
+a**=-2;
+a=~-b;
+a===-b!==+c;
+a--+-b;
+a<=-b;
+[...a]=b;
+i++<j;
@@ -0,0 +1,34 @@
+var re = /ab+c/gi;
+var slashes = /[/\]]+\/x/;
+a = b / c / d;
+if (re) {
+  /foo/.test(s);
+}
+f(/=/, [/a/], {k: /b/m});
+y = (a) / 2 / 1;
+z = {} / 2;
+w = !/x/u && typeof /y/;
+y = stats.in / total;
+y = p.new / 2 / 1;
+y = a.return / 2 / 1;
+y = a?.typeof / 2;
+var of = 4;
+y = of / 2 / 1;
+for (x of /a/g) ;
+function* g() {
+  yield /a/g;
+}
+y = function () {} / 2 / 1;
+y = class {} / 2 / 1;
+y = async function (a = function () {}) {} / 2;
+function f() {}
+/a/g.test(s);
+class C {}
+/a/g.test(s);
+async function h() {
+  await /a/g;
+}
+function k(yield, await) {
+  return yield / 2 / await;
+}
+for (var of of /a/g) ;
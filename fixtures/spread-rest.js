@@ -0,0 +1,9 @@
+var all = [...head, middle, ...tail];
+call(first, ...rest);
+new Thing(...args);
+var merged = {...defaults, size: 1};
+function f(a, b = 2, ...others) { return others; }
+var g = (x = 1, ...ys) => ys;
+var h = async (...items) => items;
+[first, ...remaining] = list;
+({a: one, ...leftover} = source);
@@ -0,0 +1,13 @@
+'abc';
+"a\"b";
+'it\'s';
+"\n\t\r\b\f\v\0";
+"é";
+"\x41\x62";
+"A\u{1F600}\u{0000041}";
+"😀";
+"line \
+continued";
+'\101\7\08';
+"\a\8\9";
+"\\";
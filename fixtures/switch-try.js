@@ -0,0 +1,38 @@
+switch (action.type) {
+  case "add":
+    total += action.value;
+    break;
+  case "reset":
+  case "clear": {
+    total = 0;
+    break;
+  }
+  default:
+    log(action);
+}
+switch (x) {}
+loop: while (true) {
+  switch (y) {
+    case 1:
+      continue loop;
+    default:
+      break loop;
+  }
+}
+try {
+  risky();
+} catch (err) {
+  throw new Error("failed: " + err.message);
+} finally {
+  cleanup();
+}
+try {
+  parse();
+} catch {
+  fallback();
+}
+try {
+  run();
+} finally {
+  done = true;
+}
@@ -0,0 +1,12 @@
+var empty = ``;
+var plain = `hello\nworld`;
+var line = `a
+b`;
+var sub = `x${a}y${b + 1}z`;
+var nested = `outer ${`inner ${c}`} ${ {k: 1}.k }`;
+var msg = t`Hello ${name}, you have ${count} messages`;
+var member = i18n.t`key`;
+var bad = String.raw`\unicode and \01`;
+var call = f(`a`)`b`;
+var slash = `${a}` / 2;
+var re = `${/x/g}`;
@@ -0,0 +1,326 @@
+package jaess
+
+import (
+	"errors"
+	"math"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// decodes a quoted string literal into its value
+// location is the position of the opening quote, used to report bad escapes
+func DecodeStringLiteral(raw string, location Cursor) (string, *SyntaxError) {
+	var buf strings.Builder
+	location._IncrementByRune(rune(raw[0]))
+	body := raw[1 : len(raw)-1]
+
+	for i := 0; i < len(body); {
+		r, size := utf8.DecodeRuneInString(body[i:])
+		if r != '\\' {
+			buf.WriteRune(r)
+			location._IncrementByRune(r)
+			i += size
+			continue
+		}
+
+		value, length, err := _DecodeEscapeSequence(body[i:], location)
+		if err != nil {
+			return "", err
+		}
+		buf.WriteString(value)
+		var prev rune
+		for _, er := range body[i : i+length] {
+			location._IncrementAfterRune(prev, er)
+			prev = er
+		}
+		i += length
+	}
+
+	return buf.String(), nil
+}
+
+// decodes one escape sequence at the start of s, which begins with a backslash
+// returns the decoded value and the length of the sequence in bytes
+func _DecodeEscapeSequence(s string, location Cursor) (string, int, *SyntaxError) {
+	if len(s) < 2 {
+		return "", 0, &SyntaxError{"invalid escape sequence", location}
+	}
+	r, size := utf8.DecodeRuneInString(s[1:])
+	length := 1 + size
+
+	switch r {
+	case 'n':
+		return "\n", length, nil
+	case 't':
+		return "\t", length, nil
+	case 'r':
+		return "\r", length, nil
+	case 'b':
+		return "\b", length, nil
+	case 'f':
+		return "\f", length, nil
+	case 'v':
+		return "\v", length, nil
+
+	// line continuations
+	case '\r':
+		if strings.HasPrefix(s[length:], "\n") {
+			length += 1
+		}
+		return "", length, nil
+	case '\n', '\u2028', '\u2029':
+		return "", length, nil
+
+	case 'x':
+		if len(s) < 4 || !IsHexDigitRune(rune(s[2])) || !IsHexDigitRune(rune(s[3])) {
+			return "", 0, &SyntaxError{"invalid hexadecimal escape sequence", location}
+		}
+		return string(rune(_HexValue(s[2:4]))), 4, nil
+
+	case 'u':
+		cp, length, ok := _DecodeUnicodeEscape(s)
+		if !ok {
+			return "", 0, &SyntaxError{"invalid Unicode escape sequence", location}
+		}
+		if cp > utf8.MaxRune {
+			return "", 0, &SyntaxError{"undefined Unicode code-point", location}
+		}
+		if utf16.IsSurrogate(rune(cp)) && cp < 0xDC00 {
+			// combine a surrogate pair written as two escapes
+			low, lowLength, ok := _DecodeUnicodeEscape(s[length:])
+			if ok && low >= 0xDC00 && low <= 0xDFFF {
+				return string(utf16.DecodeRune(rune(cp), rune(low))), length + lowLength, nil
+			}
+		}
+		// lone surrogates cannot be represented in a go string
+		return string(rune(cp)), length, nil
+
+	case '0', '1', '2', '3', '4', '5', '6', '7':
+		// legacy octal, up to three digits with a value of at most 0377
+		maxLength := 3
+		if r > '3' {
+			maxLength = 2
+		}
+		value := 0
+		digits := 0
+		for digits < maxLength && 1+digits < len(s) && s[1+digits] >= '0' && s[1+digits] <= '7' {
+			value = value*8 + int(s[1+digits]-'0')
+			digits += 1
+		}
+		return string(rune(value)), 1 + digits, nil
+	}
+
+	// non-escape characters stand for themselves, including \8 and \9
+	return string(r), length, nil
+}
+
+// decodes a \uXXXX or \u{X...} sequence at the start of s
+// returns the code point, the length of the sequence, and whether it was well formed
+func _DecodeUnicodeEscape(s string) (int, int, bool) {
+	if len(s) < 3 || s[0] != '\\' || s[1] != 'u' {
+		return 0, 0, false
+	}
+
+	if s[2] == '{' {
+		end := strings.IndexByte(s, '}')
+		if end < 4 {
+			return 0, 0, false
+		}
+		digits := s[3:end]
+		for _, r := range digits {
+			if !IsHexDigitRune(r) {
+				return 0, 0, false
+			}
+		}
+		// leading zeros are allowed, so trim them before checking the range
+		trimmed := strings.TrimLeft(digits, "0")
+		if len(trimmed) > 6 {
+			return utf8.MaxRune + 1, end + 1, true
+		}
+		return _HexValue(digits), end + 1, true
+	}
+
+	if len(s) < 6 {
+		return 0, 0, false
+	}
+	for _, r := range s[2:6] {
+		if !IsHexDigitRune(r) {
+			return 0, 0, false
+		}
+	}
+	return _HexValue(s[2:6]), 6, true
+}
+
+// value of a string of hex digits
+func _HexValue(s string) int {
+	value := 0
+	for _, r := range s {
+		switch {
+		case r >= '0' && r <= '9':
+			value = value*16 + int(r-'0')
+		case r >= 'a' && r <= 'f':
+			value = value*16 + int(r-'a') + 10
+		case r >= 'A' && r <= 'F':
+			value = value*16 + int(r-'A') + 10
+		}
+	}
+	return value
+}
+
+var (
+	decimalLiteralRegexp       = regexp.MustCompile(`^(?:0|[1-9](?:_?[0-9])*)?(?:\.(?:[0-9](?:_?[0-9])*)?)?(?:[eE][+-]?[0-9](?:_?[0-9])*)?$`)
+	nonOctalDecimalRegexp      = regexp.MustCompile(`^0[0-7]*[89][0-9]*(?:\.[0-9]*)?(?:[eE][+-]?[0-9]+)?$`)
+	legacyOctalRegexp          = regexp.MustCompile(`^0[0-7]+$`)
+	prefixedIntegerRegexp      = regexp.MustCompile(`^0[xXoObB]`)
+	hexIntegerRegexp           = regexp.MustCompile(`^0[xX][0-9a-fA-F](?:_?[0-9a-fA-F])*$`)
+	octalIntegerRegexp         = regexp.MustCompile(`^0[oO][0-7](?:_?[0-7])*$`)
+	binaryIntegerRegexp        = regexp.MustCompile(`^0[bB][01](?:_?[01])*$`)
+	decimalBigIntIntegerRegexp = regexp.MustCompile(`^(?:0|[1-9](?:_?[0-9])*)$`)
+)
+
+// numeric literals with the BigInt suffix
+func IsBigIntLiteral(raw string) bool {
+	return strings.HasSuffix(raw, "n")
+}
+
+// computes the value of a numeric literal
+// location is the start of the literal, used to report malformed literals
+func DecodeNumberLiteral(raw string, location Cursor) (float64, *SyntaxError) {
+	switch {
+	case hexIntegerRegexp.MatchString(raw):
+		return _IntegerValue(raw[2:], 16), nil
+	case octalIntegerRegexp.MatchString(raw):
+		return _IntegerValue(raw[2:], 8), nil
+	case binaryIntegerRegexp.MatchString(raw):
+		return _IntegerValue(raw[2:], 2), nil
+	case legacyOctalRegexp.MatchString(raw):
+		return _IntegerValue(raw[1:], 8), nil
+	case nonOctalDecimalRegexp.MatchString(raw):
+	case strings.ContainsAny(raw, "0123456789") && decimalLiteralRegexp.MatchString(raw):
+	default:
+		return 0, &SyntaxError{"invalid numeric literal", location}
+	}
+
+	f, err := strconv.ParseFloat(strings.Replace(raw, "_", "", -1), 64)
+	if err != nil && !errors.Is(err, strconv.ErrRange) {
+		return 0, &SyntaxError{"invalid numeric literal", location}
+	}
+	// out of range literals are infinite, as in javascript
+	return f, nil
+}
+
+// checks a BigInt literal, returning its digits without the suffix or separators
+// location is the start of the literal, used to report malformed literals
+func DecodeBigIntLiteral(raw string, location Cursor) (string, *SyntaxError) {
+	digits := strings.TrimSuffix(raw, "n")
+	switch {
+	case hexIntegerRegexp.MatchString(digits):
+	case octalIntegerRegexp.MatchString(digits):
+	case binaryIntegerRegexp.MatchString(digits):
+	case decimalBigIntIntegerRegexp.MatchString(digits):
+	default:
+		return "", &SyntaxError{"invalid BigInt literal", location}
+	}
+	return strings.Replace(digits, "_", "", -1), nil
+}
+
+// value of an integer in the given base, rounded to the nearest float64
+func _IntegerValue(digits string, base int) float64 {
+	n, ok := new(big.Int).SetString(strings.Replace(digits, "_", "", -1), base)
+	if !ok {
+		return math.NaN()
+	}
+	f, _ := new(big.Float).SetInt(n).Float64()
+	return f
+}
+
+// splits a regular expression literal into its pattern and flags
+// location is the start of the literal, used to report invalid flags
+func DecodeRegexLiteral(raw string, location Cursor) (string, string, *SyntaxError) {
+	end := strings.LastIndexByte(raw, '/')
+	pattern := raw[1:end]
+	flags := raw[end+1:]
+
+	seen := ""
+	for _, r := range flags {
+		if !strings.ContainsRune("dgimsuyv", r) || strings.ContainsRune(seen, r) {
+			return "", "", &SyntaxError{"invalid regular expression flags", location}
+		}
+		seen += string(r)
+	}
+	if strings.ContainsRune(flags, 'u') && strings.ContainsRune(flags, 'v') {
+		return "", "", &SyntaxError{"invalid regular expression flags", location}
+	}
+
+	return pattern, flags, nil
+}
+
+// the text of a template token between its delimiters, with line terminators normalized,
+// and whether the token ends its template
+func TemplateElementRaw(value string) (string, bool) {
+	text, tail := _TemplateElementText(value)
+	text = strings.Replace(text, "\r\n", "\n", -1)
+	return strings.Replace(text, "\r", "\n", -1), tail
+}
+
+// decodes the text of a template token into its cooked value
+// location is the start of the token, used to report bad escapes
+func DecodeTemplateElement(value string, location Cursor) (string, *SyntaxError) {
+	var buf strings.Builder
+	location._IncrementByRune(rune(value[0]))
+	text, _ := _TemplateElementText(value)
+
+	for i := 0; i < len(text); {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		if r == '\r' {
+			// \r\n and \r are both read as \n
+			if strings.HasPrefix(text[i+size:], "\n") {
+				size += 1
+			}
+			buf.WriteRune('\n')
+			location._IncrementByRune('\n')
+			i += size
+			continue
+		}
+		if r != '\\' {
+			buf.WriteRune(r)
+			location._IncrementByRune(r)
+			i += size
+			continue
+		}
+
+		// templates have no legacy octal escapes, only \0 not followed by a digit
+		if _IsDecimalDigitAt(text, i+1) && (text[i+1] != '0' || _IsDecimalDigitAt(text, i+2)) {
+			return "", &SyntaxError{"octal escape sequences are not allowed in template literals", location}
+		}
+		value, length, err := _DecodeEscapeSequence(text[i:], location)
+		if err != nil {
+			return "", err
+		}
+		buf.WriteString(value)
+		var prev rune
+		for _, er := range text[i : i+length] {
+			location._IncrementAfterRune(prev, er)
+			prev = er
+		}
+		i += length
+	}
+
+	return buf.String(), nil
+}
+
+// strips the backtick or brace before, and the backtick or "${" after the text of a template token
+func _TemplateElementText(value string) (string, bool) {
+	if strings.HasSuffix(value, "${") && len(value) >= 3 {
+		return value[1 : len(value)-2], false
+	}
+	return value[1 : len(value)-1], true
+}
+
+func _IsDecimalDigitAt(s string, i int) bool {
+	return i < len(s) && s[i] >= '0' && s[i] <= '9'
+}
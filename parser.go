@@ -3,13 +3,78 @@ package jaess
 import (
 	// "fmt"
 	"io"
-	"strconv"
 	"strings"
 )
 
+// deepest nesting of statements and expressions the parser will recurse into
+const maxNestingDepth = 4096
+
 // Parser instance, consumes a TokenScanner
 type Parser struct {
 	scanner *TokenScanner
+	depth   int
+	lastEnd Cursor
+	prevEnd Cursor
+	scanErr error
+	labels  []_Label
+	scopes  []*_Scope
+	classes []*_Class
+	// the in operator is not allowed, while parsing the head of a for statement
+	noIn bool
+	// where super may appear, in the function being parsed
+	superCall     bool
+	superProperty bool
+	// where arguments may not appear, in a class field initializer or static block,
+	// including the arrow functions inside them
+	noArguments bool
+	// whether await and yield are operators, in an async function or generator,
+	// and whether its params are being read, where they may not appear
+	inAsync     bool
+	inGenerator bool
+	inParams    bool
+	// await is reserved directly inside a class static block, though not an operator
+	inStaticBlock bool
+	// where shorthand properties had initializers, which are errors
+	// unless their object literal is reinterpreted as a pattern
+	shorthandInits []Cursor
+	// spread elements followed by a comma, which cannot become rest elements
+	spreadCommas map[*SpreadElement]bool
+	// names a module exports, and the local bindings its export lists refer to
+	exports      map[string]bool
+	exportLocals []*Token
+	// comments read so far, and those waiting to be attached to nodes
+	comments       []*Comment
+	leading        []*Comment
+	trailing       []*Comment
+	commentTargets []_CommentTarget
+
+	// a token put back after peeking past it
+	unread   *Token
+	lastRead *Token
+
+	// attach loc, with start and end lines and columns, to every node
+	Loc bool
+	// attach range, with start and end offsets, to every node
+	Range bool
+	// fill the defaults and rest of functions as older Esprima did,
+	// rather than keeping AssignmentPattern and RestElement params
+	LegacyParams bool
+	// "module" to parse an ES module, with imports and exports,
+	// rather than a script
+	SourceType string
+	// collect every comment into the comments of the program
+	Comment bool
+	// attach comments to the nodes around them as leading, trailing
+	// and inner comments, the way Esprima's attachComment does
+	AttachComment bool
+}
+
+// a statement that break or continue may refer to, unnamed for loops themselves
+type _Label struct {
+	Name string
+	Kind string
+	// where the labeled body starts, for labels applying to the same statement
+	bodyStart Cursor
 }
 
 // parses a string into an AstNode{type:Program,...}
@@ -18,10 +83,18 @@ func Parse(source string) (*Program, error) {
 	return parser.Parse()
 }
 
+// parses a string as an ES module into an AstNode{type:Program,...}
+func ParseModule(source string) (*Program, error) {
+	parser := NewParser(strings.NewReader(source))
+	parser.SourceType = "module"
+	return parser.Parse()
+}
+
 // create a new parser
 func NewParser(input io.RuneScanner) *Parser {
 	parser := new(Parser)
 	parser.scanner = NewTokenScanner(input)
+	parser.enterScope(true)
 	return parser
 }
 
@@ -29,6 +102,11 @@ func NewParser(input io.RuneScanner) *Parser {
 func (self *Parser) Parse() (*Program, error) {
 	node := new(Program)
 	node.Type = PROGRAM
+	node.SourceType = "script"
+	if self.SourceType == "module" {
+		node.SourceType = "module"
+	}
+	start := self.startLocation()
 	for {
 		n, err := self.Next()
 		if err != nil {
@@ -40,863 +118,3980 @@ func (self *Parser) Parse() (*Program, error) {
 			break
 		}
 	}
+	self.finishNode(node, start)
+	if self.Comment {
+		node.Comments = self.comments
+	}
 	return node, nil
 }
 
 // parses forward and returns the next statement
 func (self *Parser) Next() (AstNode, error) {
-	return self.parseStatement()
+	if self.SourceType != "module" {
+		return self.parseStatement()
+	}
+	// function declarations at the top of a module are lexical,
+	// and await may be used outside async functions
+	self.scopes[0].lexicalFunctions = true
+	self.inAsync = true
+	node, err := self.parseModuleItem()
+	if node == nil && err == nil {
+		// export lists may name bindings declared after them
+		err = self.checkExportLocals()
+	}
+	return node, err
 }
 
-// parses the next statement
-func (self *Parser) parseStatement() (AstNode, error) {
-	var token *Token
-	var err error
-
-	var node AstNode
-
-	for {
-		token, err = self.scanner.Next()
-		if token == nil || err != nil {
-			return nil, err
-		}
-
-		switch token.Type {
-		case COMMENT:
-			continue
-		case NEWLINE:
-			continue
-		}
-		if token.Value == ";" {
-			self.scanner.UnNext()
-			node, err = self.parseEmptyStatement()
-			break
-		}
-		if token.Value == "if" {
-			self.scanner.UnNext()
-			node, err = self.parseIfStatement()
-			break
-		}
-		if token.Value == "for" {
-			self.scanner.UnNext()
-			node, err = self.parseForStatement()
-			break
-		}
-		if token.Type == NUMBER || token.Type == STRING || token.Type == DELIMITER {
-			self.scanner.UnNext()
-			node, err = self.parseExpressionStatement()
-			break
+// gets the next raw token, including comments and newlines
+func (self *Parser) readToken() (*Token, error) {
+	token := self.unread
+	self.unread = nil
+	if token == nil {
+		if self.scanErr != nil {
+			return nil, self.scanErr
 		}
-		if token.Type == ATOM {
-			self.scanner.UnNext()
-			if token.Value == "function" {
-				node, err = self.parseFunctionDeclaration()
-			} else if token.Value == "return" {
-				node, err = self.parseReturnStatement()
-			} else if token.Value == "var" {
-				node, err = self.parseVariableDeclaration()
-			} else {
-				node, err = self.parseExpressionStatement()
-			}
-			break
+		var err error
+		token, err = self.scanner.Next()
+		if err != nil {
+			self.scanErr = self.scannerError(err)
+			return nil, self.scanErr
 		}
-
-		perr := NewParseError("parser error \"%s\"(%s)", token.Value, token.Type).SetLocation(token.Location)
-		panic(perr) // return nil,
-	}
-
-	if err != nil {
-		return nil, err
 	}
-	if node == nil {
-		return nil, NewParseError("parser error nil -- \"%s\"(%s)", token.Value, token.Type).SetLocation(token.Location)
+	if token != nil && token.Type != COMMENT && token.Type != NEWLINE {
+		self.lastRead = token
+		self.prevEnd = self.lastEnd
+		self.lastEnd = token.End()
 	}
-
-	for {
-		token, terr := self.scanner.Peek()
-		if terr != nil {
-			return nil, terr
-		}
-
-		if token == nil {
-			break
-		} else if token.Type == COMMENT {
-			_, _ = self.scanner.Next()
-			continue
-		} else if token.Value == ";" || token.Value == "\n" {
-			_, _ = self.scanner.Next()
-			break
-	  } else if token.Value == "}" {
-	  	break
-		} else {
-			perr := NewParseError("parser error: %s...\"%s\"(%s)", node.AstType(), token.Value, token.Type)
-			panic(perr.SetLocation(token.Location))
-			// return nil, perr.SetLocation(token.Location)
-		}
+	if token != nil && token.Type == COMMENT && (self.Comment || self.AttachComment) {
+		self.addComment(token)
 	}
-
-	return node, err
-
+	return token, nil
 }
 
-func (self *Parser) parseEmptyStatement() (AstNode, error) {
-	node := new(EmptyStatement)
-	node.Type = EMPTY_STATEMENT
-	return node, nil
+// puts back the last token, other than comments and newlines, read
+// the token peeked after it, if any, is still read next
+func (self *Parser) unreadToken() {
+	self.unread = self.lastRead
+	self.lastEnd = self.prevEnd
 }
 
-// parses a BlockStatement from start
-func (self *Parser) parseBlockStatement() (AstNode, error) {
-	token, err := self.scanner.Next()
-	if err != nil {
-		return nil, err
+// peeks at the next raw token, including comments and newlines
+func (self *Parser) peekRawToken() (*Token, error) {
+	if self.unread != nil {
+		return self.unread, nil
 	}
-	if token.Value != "{" {
-		err := NewParseError("cannot parse BLOCK_STATEMENT<<\"%s\"(%s)", token.Value, token.Type)
-		return nil, err.SetLocation(self.scanner.Location)
+	if self.scanErr != nil {
+		return nil, self.scanErr
 	}
+	token, err := self.scanner.Peek()
+	if err != nil {
+		// the scanner cannot resume after an error, so it is kept for every later read
+		self.scanErr = self.scannerError(err)
+		return nil, self.scanErr
+	}
+	return token, nil
+}
 
-	node := new(BlockStatement)
-	node.Type = BLOCK_STATEMENT
-
+// gets the next token, skipping comments and newlines
+func (self *Parser) nextToken() (*Token, error) {
 	for {
-		nextToken, err := self.scanner.Next()
-
-		if err != nil {
+		token, err := self.readToken()
+		if token == nil || err != nil {
 			return nil, err
 		}
-		if nextToken == nil {
-			break
-		}
-		if "}" == nextToken.Value {
-			break
-		}
-		if "\n" == nextToken.Value {
-			continue
-		}
-		if COMMENT == nextToken.Type {
-			continue
-		}
-
-		self.scanner.UnNext()
-		innerStatement, err := self.parseStatement()
-		if err != nil {
-			return nil, err
+		if token.Type != COMMENT && token.Type != NEWLINE {
+			return token, nil
 		}
-		node.Body = append(node.Body, innerStatement)
-
 	}
+}
 
+// peeks at the next token, skipping comments and newlines
+func (self *Parser) peekToken() (*Token, error) {
 	for {
-		token, terr := self.scanner.Peek()
-		if terr != nil {
-			return nil, terr
+		token, err := self.peekRawToken()
+		if token == nil || err != nil {
+			return nil, err
 		}
-
-		if token == nil || token.Value == ";" || token.Value == ")" {
-			break
-		} else if token.Type == COMMENT {
-			_, _ = self.scanner.Next()
-			continue
-		} else if token.Value == "\n" {
-			_, _ = self.scanner.Next()
-			break
-		} else {
-			perr := NewParseError("parser error: BLOCK_STATEMENT...\"%s\"(%s)", token.Value, token.Type)
-			panic(perr.SetLocation(token.Location))
-			// return nil, perr.SetLocation(token.Location)
+		if token.Type != COMMENT && token.Type != NEWLINE {
+			return token, nil
 		}
+		_, _ = self.readToken()
 	}
-
-	return node, nil
 }
 
-// parses and wraps an expression into a statement node
-func (self *Parser) parseExpressionStatement() (AstNode, error) {
-	node := new(ExpressionStatement)
-	node.Type = EXPRESSION_STATEMENT
-
-	exprNode, err := self.parseExpression()
-	if err != nil {
+// peeks at the next token, or nil if a line break comes before it
+func (self *Parser) peekTokenOnSameLine() (*Token, error) {
+	token, err := self.peekToken()
+	if token == nil || err != nil || token.NewlineBefore {
 		return nil, err
 	}
-	node.Expression = exprNode
+	return token, nil
+}
 
-	nextNode, err := self.scanner.Peek()
+// gets the next token, which must have the given value
+func (self *Parser) expectToken(value string, context string) (*Token, error) {
+	token, err := self.nextToken()
 	if err != nil {
 		return nil, err
 	}
-	if nextNode != nil && nextNode.Value == ";" {
-		self.scanner.Next()
+	if token == nil || token.Value != value {
+		return nil, self.unexpectedToken(token, context)
 	}
-
-	return node, nil
+	return token, nil
 }
 
-// parses if statemetn
-func (self *Parser) parseIfStatement() (AstNode, error) {
-	node := new(IfStatement)
-	node.Type = IF_STATEMENT
+// location of the next token, where a node parsed from here starts
+func (self *Parser) startLocation() Cursor {
+	token, err := self.peekToken()
+	if err != nil || token == nil {
+		// errors are reported again when the token is read
+		return self.scanner.Location
+	}
+	return token.Location
+}
 
-	token, err := self.scanner.Next()
-	if err != nil {
-		return nil, err
+// records the source span of a node, from start to the end of the last token read,
+// when locations or ranges are enabled, and attaches comments around it if asked to
+func (self *Parser) finishNode(node AstNode, start Cursor) AstNode {
+	if !self.Loc && !self.Range && !self.AttachComment {
+		return node
 	}
-	if token.Value != "if" {
-		err := NewParseError("cannot parse IF_STATEMENT<<%s", token.Value)
-		return nil, err.SetLocation(token.Location)
+	meta, ok := node.(interface{ Meta() *AstNodeMeta })
+	if !ok {
+		return node
 	}
 
-	token, err = self.scanner.Next()
-	if err != nil {
-		return nil, err
+	end := self.lastEnd
+	if end.Offset < start.Offset {
+		// nothing was read, as in an empty program
+		end = start
 	}
-	if token.Value != "(" {
-		err := NewParseError("cannot parse IF_STATEMENT<<if %s", token.Value)
-		return nil, err.SetLocation(token.Location)
+	self.setLocation(meta.Meta(), start, end)
+	if self.AttachComment {
+		self.attachComments(node, meta.Meta(), start.Offset, end.Offset)
 	}
+	return node
+}
 
-	node.Test, err = self.parseExpression()
-	if err != nil {
-		return nil, err
+// records the source span from start to end, when locations or ranges are enabled
+func (self *Parser) setLocation(meta *AstNodeMeta, start Cursor, end Cursor) {
+	if self.Range {
+		meta.Range = &[2]int{start.Offset, end.Offset}
 	}
-
-	token, err = self.scanner.Next()
-	if err != nil {
-		return nil, err
+	if self.Loc {
+		meta.Loc = &SourceLocation{start.Position(), end.Position()}
 	}
-	if token.Value != ")" {
-		err := NewParseError("cannot parse IF_STATEMENT<<(...%s", token.Value)
-		return nil, err.SetLocation(token.Location)
+}
+
+// creates an error for an unexpected token, or for the end of input if token is nil
+func (self *Parser) unexpectedToken(token *Token, context string) *ParseError {
+	if token == nil {
+		return NewParseError("cannot parse %s<<EOF", context).SetLocation(self.scanner.Location)
 	}
+	err := NewParseError("cannot parse %s<<'%s'(%s)", context, token.Value, token.Type)
+	return err.SetLocation(token.Location)
+}
 
-	node.Consequent, err = self.parseBlockStatement()
-	if err != nil {
-		return nil, err
+// converts errors from the scanner into parse errors
+func (self *Parser) scannerError(err error) error {
+	switch err := err.(type) {
+	case *SyntaxError:
+		return NewParseError("%s", err.Message).SetLocation(err.Location)
+	case ScannerError:
+		return NewParseError("%s", err.Message).SetLocation(self.scanner.Location)
 	}
+	return err
+}
 
-	return node, nil
+// tracks recursion depth, so deeply nested input errors instead of exhausting the stack
+func (self *Parser) enterNesting() error {
+	self.depth += 1
+	if self.depth > maxNestingDepth {
+		return NewParseError("maximum nesting depth exceeded").SetLocation(self.scanner.Location)
+	}
+	return nil
 }
 
-// parses for statement
-func (self *Parser) parseForStatement() (AstNode, error) {
-	node := new(ForStatement)
-	node.Type = FOR_STATEMENT
+// leaves a level entered with enterNesting
+func (self *Parser) leaveNesting() {
+	self.depth -= 1
+}
 
-	token, err := self.scanner.Next()
-	if err != nil {
+// parses the next statement
+func (self *Parser) parseStatement() (AstNode, error) {
+	token, err := self.nextToken()
+	if token == nil || err != nil {
 		return nil, err
 	}
-	if token.Value != "for" {
-		err := NewParseError("cannot parse FOR_STATEMENT<<%s", token.Value)
-		return nil, err.SetLocation(token.Location)
-	}
+	self.unreadToken()
 
-	token, err = self.scanner.Next()
+	err = self.enterNesting()
+	defer self.leaveNesting()
 	if err != nil {
 		return nil, err
 	}
-	if token.Value != "(" {
-		err := NewParseError("cannot parse FOR_STATEMENT<<for %s", token.Value)
-		return nil, err.SetLocation(token.Location)
-	}
 
-	node.Init, err = self.parseStatement()
-	if err != nil {
-		return nil, err
+	var node AstNode
+	switch {
+	case token.Value == ";":
+		node, err = self.parseEmptyStatement()
+	case token.Value == "{":
+		node, err = self.parseBlockStatement()
+	case token.Value == "if":
+		node, err = self.parseIfStatement()
+	case token.Value == "for":
+		node, err = self.parseForStatement()
+	case token.Value == "while":
+		node, err = self.parseWhileStatement()
+	case token.Value == "do":
+		node, err = self.parseDoWhileStatement()
+	case token.Value == "break", token.Value == "continue":
+		node, err = self.parseBreakContinueStatement()
+	case token.Value == "switch":
+		node, err = self.parseSwitchStatement()
+	case token.Value == "throw":
+		node, err = self.parseThrowStatement()
+	case token.Value == "try":
+		node, err = self.parseTryStatement()
+	case token.Value == "function", token.Value == "async" && self.isAsyncFunction():
+		node, err = self.parseFunctionDeclaration(false)
+	case token.Value == "class":
+		node, err = self.parseClassDeclaration(false)
+	case token.Value == "return":
+		node, err = self.parseReturnStatement()
+	case token.Value == "var", token.Value == "const":
+		node, err = self.parseVariableDeclaration(false)
+	case token.Value == "let" && self.isLetDeclaration():
+		node, err = self.parseVariableDeclaration(false)
+	case token.Value == "import" && !self.isImportExpression():
+		err = NewParseError("import declarations may only appear at top level of a module").SetLocation(token.Location)
+	case token.Value == "export":
+		err = NewParseError("export declarations may only appear at top level of a module").SetLocation(token.Location)
+	default:
+		node, err = self.parseExpressionStatement()
 	}
-
-	token, err = self.scanner.Peek()
 	if err != nil {
 		return nil, err
 	}
-	if token.Value != ")" {
-		node.Test, err = self.parseExpression()
-		if err != nil {
-			return nil, err
-		}
 
-		token, err = self.scanner.Next()
-		if err != nil {
-			return nil, err
-		}
-		if token.Value != ")" {
-			node.Update, err = self.parseExpression()
-			if err != nil {
-				return nil, err
-			}
-			token, err = self.scanner.Next()
-			if err != nil {
-				return nil, err
-			}
-		}
+	switch node.AstType() {
+	case BLOCK_STATEMENT, FUNCTION_DECLARATION, CLASS_DECLARATION, IF_STATEMENT, FOR_STATEMENT, FOR_IN_STATEMENT,
+		FOR_OF_STATEMENT, WHILE_STATEMENT, DO_WHILE_STATEMENT, LABELED_STATEMENT, SWITCH_STATEMENT, TRY_STATEMENT:
+		// ends with a block or another statement
+		return self.finishNode(node, token.Location), nil
 	}
 
-	if token.Value != ")" {
-		err := NewParseError("cannot parse FOR_STATEMENT<<(...;...; %s", token.Value)
-		return nil, err.SetLocation(token.Location)
+	err = self.parseStatementEnd(node)
+	if err != nil {
+		return nil, err
 	}
+	return self.finishNode(node, token.Location), nil
+}
 
-	node.Body, err = self.parseBlockStatement()
+// consumes the semicolon ending a statement, which is inserted automatically
+// before a closing brace, the end of input, or a token on a later line
+func (self *Parser) parseStatementEnd(node AstNode) error {
+	token, err := self.peekToken()
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	return node, nil
-}
+	// a semicolon is consumed even on a later line, as insertion only
+	// applies where the next token is not allowed
+	switch {
+	case token != nil && token.Value == ";":
+		_, _ = self.nextToken()
+		return nil
+	case token == nil || token.Value == "}" || token.NewlineBefore:
+		return nil
+	}
 
-// parses return statement
-func (self *Parser) parseReturnStatement() (AstNode, error) {
-	var err error
+	perr := NewParseError("cannot parse %s...'%s'(%s)", node.AstType(), token.Value, token.Type)
+	return perr.SetLocation(token.Location)
+}
 
-	token, err := self.scanner.Next()
+// parses a statement at the top level of a module, where imports and exports may appear
+func (self *Parser) parseModuleItem() (AstNode, error) {
+	token, err := self.peekToken()
 	if token == nil || err != nil {
 		return nil, err
 	}
-	if token.Value != "return" {
-		err := NewParseError("cannot parse RETURN_STATEMENT<<%s:%s", token.Type, token.Value)
-		return nil, err.SetLocation(token.Location)
+	switch {
+	case token.Value == "import" && !self.isImportExpression():
+		return self.parseImportDeclaration()
+	case token.Value == "export":
+		return self.parseExportDeclaration()
 	}
+	return self.parseStatement()
+}
 
-	node := new(ReturnStatement)
-	node.Type = RETURN_STATEMENT
-	node.Argument, err = self.parseExpression()
-	if err != nil {
-		return nil, err
+// whether the import about to be read begins an import() call or import.meta,
+// rather than an import declaration
+func (self *Parser) isImportExpression() bool {
+	token, err := self.nextToken()
+	if err != nil || token == nil || token.Value != "import" {
+		if token != nil {
+			self.unreadToken()
+		}
+		return false
 	}
-	return node, nil
+	next, err := self.peekToken()
+	self.unreadToken()
+	return err == nil && next != nil && (next.Value == "(" || next.Value == ".")
 }
 
-// parses a function declaration into a statement node
-func (self *Parser) parseFunctionDeclaration() (AstNode, error) {
-	node := new(FunctionDeclaration)
-	node.Type = FUNCTION_DECLARATION
-
-	token, err := self.scanner.Next()
-	if token == nil || err != nil {
+// parses an import declaration, binding the imported names in the module scope
+func (self *Parser) parseImportDeclaration() (AstNode, error) {
+	start, err := self.expectToken("import", "IMPORT_DECLARATION")
+	if err != nil {
 		return nil, err
 	}
-	if token.Value != "function" {
-		err := NewParseError("cannot parse FUNCTION_DECLARATION<<%s:%s", token.Type, token.Value)
-		return nil, err.SetLocation(token.Location)
-	}
 
-	token, err = self.scanner.Next()
-	if token == nil {
-		err := NewParseError("cannot parse FUNCTION_DECLARATION<<EOF")
-		err.SetLocation(self.scanner.Location)
-	}
+	node := new(ImportDeclaration)
+	node.Type = IMPORT_DECLARATION
+	node.Specifiers = []AstNode{}
+
+	token, err := self.nextToken()
 	if err != nil {
 		return nil, err
 	}
+	if token == nil {
+		return nil, self.unexpectedToken(token, "IMPORT_DECLARATION")
+	}
+	if token.Type != STRING {
+		if token.Type == ATOM {
+			specifier := new(ImportDefaultSpecifier)
+			specifier.Type = IMPORT_DEFAULT_SPECIFIER
+			specifier.Local, err = self.parseImportBinding(token)
+			if err != nil {
+				return nil, err
+			}
+			node.Specifiers = append(node.Specifiers, self.finishNode(specifier, token.Location))
 
-	if token.Type == ATOM {
-		node.Id, err = self.parseIdentifier(token)
+			next, err := self.peekToken()
+			if err != nil {
+				return nil, err
+			}
+			token = nil
+			if next != nil && next.Value == "," {
+				_, _ = self.nextToken()
+				token, err = self.nextToken()
+				if err != nil {
+					return nil, err
+				}
+				if token == nil {
+					return nil, self.unexpectedToken(token, "IMPORT_DECLARATION")
+				}
+			}
+		}
+
+		if token != nil {
+			switch token.Value {
+			case "*":
+				specifier := new(ImportNamespaceSpecifier)
+				specifier.Type = IMPORT_NAMESPACE_SPECIFIER
+				_, err = self.expectToken("as", "IMPORT_NAMESPACE_SPECIFIER")
+				if err != nil {
+					return nil, err
+				}
+				local, err := self.nextToken()
+				if err != nil {
+					return nil, err
+				}
+				specifier.Local, err = self.parseImportBinding(local)
+				if err != nil {
+					return nil, err
+				}
+				node.Specifiers = append(node.Specifiers, self.finishNode(specifier, token.Location))
+			case "{":
+				specifiers, err := self.parseImportSpecifiers()
+				if err != nil {
+					return nil, err
+				}
+				node.Specifiers = append(node.Specifiers, specifiers...)
+			default:
+				return nil, self.unexpectedToken(token, "IMPORT_DECLARATION")
+			}
+		}
+
+		_, err = self.expectToken("from", "IMPORT_DECLARATION")
 		if err != nil {
 			return nil, err
 		}
-		token, err = self.scanner.Next()
+		token, err = self.nextToken()
 		if err != nil {
 			return nil, err
 		}
 	}
 
-	if token.Value != "(" {
-		err := NewParseError("cannot parse FUNCTION_DECLARATION<<function %s:%s", token.Type, token.Value)
-		return nil, err.SetLocation(token.Location)
-	}
-	node.Params, err = self.parseParamList()
-	node.Defaults = []AstNode{}
-
-	self.scanner.BeginCapture()
-	node.Body, err = self.parseBlockStatement()
+	node.Source, node.Attributes, err = self.parseModuleSource(token)
 	if err != nil {
 		return nil, err
 	}
-	capture := self.scanner.FinishCapture()
-	node.Source = _TrimFunctionSource(capture.String())
-
-	return node, nil
-}
-
-// parses a variable declaration
-func (self *Parser) parseVariableDeclaration() (AstNode, error) {
-	node := new(VariableDeclaration)
-	node.Type = VARIABLE_DECLARATION
-
-	token, err := self.scanner.Next()
-	if token == nil || err != nil {
+	err = self.parseStatementEnd(node)
+	if err != nil {
 		return nil, err
 	}
-	if token.Value != "var" {
-		err := NewParseError("cannot parse VARIABLE_DECLARATION<<%s:%s", token.Type, token.Value)
-		return nil, err.SetLocation(token.Location)
-	}
-
-	node.Kind = token.Value
+	return self.finishNode(node, start.Location), nil
+}
 
+// finishes parsing the braced list of named imports
+func (self *Parser) parseImportSpecifiers() ([]AstNode, error) {
+	specifiers := []AstNode{}
 	for {
-		token, err = self.scanner.Next()
+		token, err := self.nextToken()
 		if err != nil {
 			return nil, err
 		}
-		if token == nil {
-			if len(node.Declarations) == 0 {
-				return nil, NewParseError("cannot parse VARIABLE_DECLARATION<<EOF").SetLocation(self.scanner.Location)
-			}
-			break
-		}
-		if token.Value == ";" {
-			self.scanner.UnNext()
-			break
+		if token != nil && token.Value == "}" {
+			return specifiers, nil
 		}
 
-		if token.Type == ATOM {
-			declNode := new(VariableDeclarator)
-			declNode.Type = VARIABLE_DECLARATOR
-			declNode.Id, err = self.parseIdentifier(token)
-			if err != nil {
-				return nil, err
-			}
-			token, err = self.scanner.Peek()
+		node := new(ImportSpecifier)
+		node.Type = IMPORT_SPECIFIER
+		node.Imported, err = self.parseModuleExportName(token, "IMPORT_SPECIFIER")
+		if err != nil {
+			return nil, err
+		}
+		next, err := self.peekToken()
+		if err != nil {
+			return nil, err
+		}
+		if next != nil && next.Value == "as" {
+			_, _ = self.nextToken()
+			var local *Token
+			local, err = self.nextToken()
 			if err != nil {
 				return nil, err
 			}
-			if token != nil && token.Value == "=" {
-				_, _ = self.scanner.Next()
-				initNode, err := self.parseExpression()
-				if err != nil {
-					return nil, err
-				}
-				declNode.Init = initNode
-				node.Declarations = append(node.Declarations, declNode)
-			}
-			continue
+			node.Local, err = self.parseImportBinding(local)
+		} else if token.Type == ATOM {
+			node.Local, err = self.parseImportBinding(token)
+		} else {
+			// a string name must be given a local name
+			err = self.unexpectedToken(next, "IMPORT_SPECIFIER")
 		}
+		if err != nil {
+			return nil, err
+		}
+		specifiers = append(specifiers, self.finishNode(node, token.Location))
 
-		return nil, NewParseError("cannot parse VARIABLE_DECLARATOR<<\"%s\"(%s)", token.Value, token.Type).SetLocation(token.Location)
+		next, err = self.nextToken()
+		if err != nil {
+			return nil, err
+		}
+		if next == nil || (next.Value != "," && next.Value != "}") {
+			return nil, self.unexpectedToken(next, "IMPORT_SPECIFIER")
+		}
+		if next.Value == "}" {
+			return specifiers, nil
+		}
 	}
+}
 
-	return node, nil
+// finishes parsing the local name an import binds, declaring it in the module scope
+func (self *Parser) parseImportBinding(token *Token) (AstNode, error) {
+	if token == nil || token.Type != ATOM {
+		return nil, self.unexpectedToken(token, "IMPORT_DECLARATION")
+	}
+	err := self.declareLexical(token.Value, token.Location)
+	if err != nil {
+		return nil, err
+	}
+	return self.parseIdentifier(token)
 }
 
-// parses a list of param patterns
-func (self *Parser) parseParamList() ([]AstNode, error) {
-	paramList := []AstNode{}
+// finishes parsing a name of an import or export, which may be a string
+func (self *Parser) parseModuleExportName(token *Token, context string) (AstNode, error) {
+	if token == nil {
+		return nil, self.unexpectedToken(token, context)
+	}
+	switch token.Type {
+	case ATOM:
+		return self.parseIdentifier(token)
+	case STRING:
+		return self.parseLiteral(token)
+	}
+	return nil, self.unexpectedToken(token, context)
+}
+
+// finishes parsing the string naming an imported module, and the attributes
+// of a with clause after it
+func (self *Parser) parseModuleSource(token *Token) (AstNode, []AstNode, error) {
+	if token == nil || token.Type != STRING {
+		return nil, nil, self.unexpectedToken(token, "MODULE_SOURCE")
+	}
+	source, err := self.parseLiteral(token)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	attributes := []AstNode{}
+	token, err = self.peekToken()
+	if err != nil {
+		return nil, nil, err
+	}
+	if token == nil || token.Value != "with" {
+		return source, attributes, nil
+	}
+	_, _ = self.nextToken()
+	_, err = self.expectToken("{", "IMPORT_ATTRIBUTE")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	keys := map[string]bool{}
+	for {
+		token, err = self.nextToken()
+		if err != nil {
+			return nil, nil, err
+		}
+		if token != nil && token.Value == "}" {
+			return source, attributes, nil
+		}
+
+		node := new(ImportAttribute)
+		node.Type = IMPORT_ATTRIBUTE
+		node.Key, err = self.parseModuleExportName(token, "IMPORT_ATTRIBUTE")
+		if err != nil {
+			return nil, nil, err
+		}
+		key := _PropertyKeyName(node.Key)
+		if keys[key] {
+			return nil, nil, NewParseError("import attribute '%s' may only be given once", key).SetLocation(token.Location)
+		}
+		keys[key] = true
+		_, err = self.expectToken(":", "IMPORT_ATTRIBUTE")
+		if err != nil {
+			return nil, nil, err
+		}
+		value, err := self.nextToken()
+		if err != nil {
+			return nil, nil, err
+		}
+		if value == nil || value.Type != STRING {
+			return nil, nil, self.unexpectedToken(value, "IMPORT_ATTRIBUTE")
+		}
+		node.Value, err = self.parseLiteral(value)
+		if err != nil {
+			return nil, nil, err
+		}
+		attributes = append(attributes, self.finishNode(node, token.Location))
+
+		next, err := self.nextToken()
+		if err != nil {
+			return nil, nil, err
+		}
+		if next == nil || (next.Value != "," && next.Value != "}") {
+			return nil, nil, self.unexpectedToken(next, "IMPORT_ATTRIBUTE")
+		}
+		if next.Value == "}" {
+			return source, attributes, nil
+		}
+	}
+}
+
+// parses an export declaration, recording the names it exports
+func (self *Parser) parseExportDeclaration() (AstNode, error) {
+	start, err := self.expectToken("export", "EXPORT_DECLARATION")
+	if err != nil {
+		return nil, err
+	}
+	token, err := self.peekToken()
+	if err != nil {
+		return nil, err
+	}
+	if token == nil {
+		return nil, self.unexpectedToken(token, "EXPORT_DECLARATION")
+	}
+
+	switch {
+	case token.Value == "*":
+		return self.parseExportAllDeclaration(start)
+	case token.Value == "{":
+		return self.parseExportList(start)
+	case token.Value == "default":
+		return self.parseExportDefaultDeclaration(start)
+	case token.Value == "var", token.Value == "const", token.Value == "function", token.Value == "class":
+	case token.Value == "let" && self.isLetDeclaration():
+	case token.Value == "async" && self.isAsyncFunction():
+	default:
+		return nil, self.unexpectedToken(token, "EXPORT_DECLARATION")
+	}
+
+	node := new(ExportNamedDeclaration)
+	node.Type = EXPORT_NAMED_DECLARATION
+	node.Specifiers = []AstNode{}
+	node.Attributes = []AstNode{}
+	node.Declaration, err = self.parseStatement()
+	if err != nil {
+		return nil, err
+	}
+
+	names := []string{}
+	switch declaration := node.Declaration.(type) {
+	case *VariableDeclaration:
+		for _, declarator := range declaration.Declarations {
+			names = _CollectBoundNames(declarator.(*VariableDeclarator).Id, names)
+		}
+	case *FunctionDeclaration:
+		names = _CollectBoundNames(declaration.Id, names)
+	case *ClassDeclaration:
+		names = _CollectBoundNames(declaration.Id, names)
+	}
+	for _, name := range names {
+		err = self.declareExport(name, token.Location)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return self.finishNode(node, start.Location), nil
+}
+
+// finishes parsing a braced list of exports, which are
+// re-exported from another module when followed by from
+func (self *Parser) parseExportList(start *Token) (AstNode, error) {
+	node := new(ExportNamedDeclaration)
+	node.Type = EXPORT_NAMED_DECLARATION
+	node.Specifiers = []AstNode{}
+	node.Attributes = []AstNode{}
 
+	_, _ = self.nextToken()
+	locals := []*Token{}
 	for {
-		token, err := self.scanner.Next()
+		token, err := self.nextToken()
 		if err != nil {
 			return nil, err
 		}
-		if token.Value == ")" {
+		if token != nil && token.Value == "}" {
 			break
 		}
 
-		if token.Type == ATOM {
-			pNode, err := self.parseIdentifier(token)
+		specifier := new(ExportSpecifier)
+		specifier.Type = EXPORT_SPECIFIER
+		specifier.Local, err = self.parseModuleExportName(token, "EXPORT_SPECIFIER")
+		if err != nil {
+			return nil, err
+		}
+		specifier.Exported = specifier.Local
+		exported := token
+		next, err := self.peekToken()
+		if err != nil {
+			return nil, err
+		}
+		if next != nil && next.Value == "as" {
+			_, _ = self.nextToken()
+			exported, err = self.nextToken()
+			if err != nil {
+				return nil, err
+			}
+			specifier.Exported, err = self.parseModuleExportName(exported, "EXPORT_SPECIFIER")
 			if err != nil {
 				return nil, err
 			}
-			paramList = append(paramList, pNode)
-		} else {
-			err := NewParseError("cannot parse PARAM_LIST<<%s:%s", token.Type, token.Value)
-			return nil, err.SetLocation(token.Location)
 		}
+		err = self.declareExport(_PropertyKeyName(specifier.Exported), exported.Location)
+		if err != nil {
+			return nil, err
+		}
+		locals = append(locals, token)
+		node.Specifiers = append(node.Specifiers, self.finishNode(specifier, token.Location))
 
-		token, err = self.scanner.Next()
+		next, err = self.nextToken()
 		if err != nil {
 			return nil, err
 		}
-		if token.Value != "," {
-			if token.Value != ")" {
-				self.scanner.UnNext()
-			}
+		if next == nil || (next.Value != "," && next.Value != "}") {
+			return nil, self.unexpectedToken(next, "EXPORT_SPECIFIER")
+		}
+		if next.Value == "}" {
 			break
 		}
 	}
 
-	return paramList, nil
-}
-
-// parses a list of expressions seperated by commas
-func (self *Parser) parseArgumentList() ([]AstNode, error) {
-	nodeList := []AstNode{}
-	for {
-		token, err := self.scanner.Peek()
+	token, err := self.peekToken()
+	if err != nil {
+		return nil, err
+	}
+	if token != nil && token.Value == "from" {
+		_, _ = self.nextToken()
+		token, err = self.nextToken()
 		if err != nil {
 			return nil, err
 		}
-		if token.Value == ")" {
-			_, _ = self.scanner.Next()
-			break
+		node.Source, node.Attributes, err = self.parseModuleSource(token)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		for _, local := range locals {
+			// only names from another module may be strings
+			if local.Type != ATOM {
+				return nil, self.unexpectedToken(local, "EXPORT_SPECIFIER")
+			}
+			self.useExportLocal(local)
 		}
+	}
+
+	err = self.parseStatementEnd(node)
+	if err != nil {
+		return nil, err
+	}
+	return self.finishNode(node, start.Location), nil
+}
 
-		nextNode, err := self.parseExpression()
+// finishes parsing an export of everything another module exports
+func (self *Parser) parseExportAllDeclaration(start *Token) (AstNode, error) {
+	node := new(ExportAllDeclaration)
+	node.Type = EXPORT_ALL_DECLARATION
+
+	_, _ = self.nextToken()
+	token, err := self.nextToken()
+	if err != nil {
+		return nil, err
+	}
+	if token != nil && token.Value == "as" {
+		token, err = self.nextToken()
 		if err != nil {
 			return nil, err
 		}
-		if nextNode != nil {
-			nodeList = append(nodeList, nextNode)
+		node.Exported, err = self.parseModuleExportName(token, "EXPORT_ALL_DECLARATION")
+		if err != nil {
+			return nil, err
 		}
-
-		token, err = self.scanner.Next()
+		err = self.declareExport(_PropertyKeyName(node.Exported), token.Location)
 		if err != nil {
 			return nil, err
 		}
-		if token.Value != "," {
-			if token.Value != ")" {
-				self.scanner.UnNext()
-			}
-			break
+		token, err = self.nextToken()
+		if err != nil {
+			return nil, err
 		}
 	}
-	return nodeList, nil
+	if token == nil || token.Value != "from" {
+		return nil, self.unexpectedToken(token, "EXPORT_ALL_DECLARATION")
+	}
+
+	token, err = self.nextToken()
+	if err != nil {
+		return nil, err
+	}
+	node.Source, node.Attributes, err = self.parseModuleSource(token)
+	if err != nil {
+		return nil, err
+	}
+	err = self.parseStatementEnd(node)
+	if err != nil {
+		return nil, err
+	}
+	return self.finishNode(node, start.Location), nil
 }
 
-// parses from the start of an expression
-func (self *Parser) parseExpression() (AstNode, error) {
-  return self.parseExpressionUntil([]string{})
+// finishes parsing an export default of a declaration or an expression
+func (self *Parser) parseExportDefaultDeclaration(start *Token) (AstNode, error) {
+	node := new(ExportDefaultDeclaration)
+	node.Type = EXPORT_DEFAULT_DECLARATION
+
+	token, _ := self.nextToken()
+	err := self.declareExport("default", token.Location)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err = self.peekToken()
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case token != nil && (token.Value == "function" || token.Value == "async" && self.isAsyncFunction()):
+		node.Declaration, err = self.parseFunctionDeclaration(true)
+		if err == nil {
+			self.finishNode(node.Declaration, token.Location)
+		}
+	case token != nil && token.Value == "class":
+		node.Declaration, err = self.parseClassDeclaration(true)
+		if err == nil {
+			self.finishNode(node.Declaration, token.Location)
+		}
+	default:
+		node.Declaration, err = self.parseMaybeAssignment()
+		if err == nil {
+			err = self.parseStatementEnd(node)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return self.finishNode(node, start.Location), nil
 }
 
-// parses an expression until a match in an exclude list, or normal end of expression
-func (self *Parser) parseExpressionUntil(excludeList []string) (AstNode, error) {
+func (self *Parser) parseEmptyStatement() (AstNode, error) {
+	node := new(EmptyStatement)
+	node.Type = EMPTY_STATEMENT
+	return node, nil
+}
 
-	var node AstNode
-	var err error
+// parses a BlockStatement from start, in a new scope
+func (self *Parser) parseBlockStatement() (AstNode, error) {
+	self.enterScope(false)
+	defer self.leaveScope()
+	return self.parseBlock()
+}
 
-	for {
-		var token *Token
-		token, err = self.scanner.Next()
+// parses the braces and statements of a block in the current scope
+func (self *Parser) parseBlock() (AstNode, error) {
+	start, err := self.expectToken("{", "BLOCK_STATEMENT")
+	if err != nil {
+		return nil, err
+	}
 
-		// fmt.Printf("\x1b[90mparsing expr<<%s %+v\x1b[0m\n", token.Value, node)
+	node := new(BlockStatement)
+	node.Type = BLOCK_STATEMENT
+	node.Body = []AstNode{}
 
+	for {
+		token, err := self.peekToken()
 		if err != nil {
 			return nil, err
 		}
 		if token == nil {
-			if node == nil {
-				perr := NewParseError("cannot parse EXPRESSION<<EOF")
-				return nil, perr.SetLocation(token.Location)
-			}
-			return node, nil
+			return nil, self.unexpectedToken(token, "BLOCK_STATEMENT")
 		}
-
-		switch token.Type {
-		case NEWLINE, COMMENT:
-			continue
+		if token.Value == "}" {
+			_, _ = self.nextToken()
+			break
 		}
 
-		// hack for bin exprs todo fix
-		if node != nil {
-			for _, exC := range excludeList {
-				if exC == token.Value {
-					self.scanner.UnNext()
-					return node, nil
-				}
-			}
+		innerStatement, err := self.parseStatement()
+		if err != nil {
+			return nil, err
 		}
+		node.Body = append(node.Body, innerStatement)
+	}
 
-		switch token.Value {
-		case ";", ")", ",", ":", "}", "]":
-			self.scanner.UnNext()
-			return node, nil
-		}
+	return self.finishNode(node, start.Location), nil
+}
 
-		if node == nil {
-			switch token.Value {
-			case "(":
-				node, err = self.parseExpression()
-				if err != nil {
-					return nil, err
-				}
-				token, nerr := self.scanner.Next()
-				if nerr != nil {
-					return nil, err
-				}
-				if token.Value != ")" {
-					perr := NewParseError("cannot parse (EXPRESSION...<<\"%s\"(%s)", token.Value, token.Type)
-					return nil, perr.SetLocation(token.Location)
-				}
-				continue
-			case "{":
-				node, err = self.parseObjectExpression(token)
-				if err != nil {
-					return nil, err
-				}
-				continue
-			case "[":
-				node, err = self.parseArrayExpression(token)
-				if err != nil {
-					return nil, err
-				}
-				continue
-			}
+// parses and wraps an expression into a statement node
+// an identifier followed by a colon is a label instead
+func (self *Parser) parseExpressionStatement() (AstNode, error) {
+	node := new(ExpressionStatement)
+	node.Type = EXPRESSION_STATEMENT
 
-			switch token.Type {
-			case NUMBER:
-				node, err = self.parseLiteral(token)
-				continue
-			case STRING:
-				node, err = self.parseLiteral(token)
-				continue
-			case OPERATOR:
-				if token.Value == "++" || token.Value == "--" {
-					node, err = self.parseUpdateExpression(token)
-					continue
-				}
-				if IsUnaryOperator(token) {
-					node, err = self.parseUnaryExpression(token)
-					continue
-				}
-			case ATOM:
-				switch token.Value {
-				case "null":
-					node, err = self.parseLiteral(token)
-				case "this":
-					node, err = self.parseThisExpression(token)
-				case "new":
-					node, err = self.parseNewExpression(token)
-				case "function":
-					node, err = self.parseFunctionExpression(token)
-				case "delete":
-					node, err = self.parseUnaryExpression(token)
-				case "instanceof":
-				default:
-					node, err = self.parseIdentifier(token)
-				}
-				if err != nil {
-					return nil, err
-				}
-				continue
-			}
+	token, err := self.peekToken()
+	if err != nil {
+		return nil, err
+	}
 
-		} else {
-			switch token.Value {
-			case "(":
-				node, err = self.parseCallExpression(node)
-				if err != nil {
-					return nil, err
-				}
-				continue
-			case "[":
-				self.scanner.UnNext()
-				node, err = self.parseMemberExpression(node)
-				if err != nil {
+	exprNode, err := self.parseExpression()
+	if err != nil {
+		return nil, err
+	}
+
+	if identifier, ok := exprNode.(*Identifier); ok && token.Type == ATOM {
+		next, err := self.peekToken()
+		if err != nil {
+			return nil, err
+		}
+		if next != nil && next.Value == ":" {
+			_, _ = self.nextToken()
+			return self.parseLabeledStatement(identifier, token)
+		}
+	}
+
+	node.Expression = exprNode
+	return node, nil
+}
+
+// finishes parsing a labeled statement given its label
+func (self *Parser) parseLabeledStatement(label *Identifier, token *Token) (AstNode, error) {
+	node := new(LabeledStatement)
+	node.Type = LABELED_STATEMENT
+	node.Label = label
+
+	for _, active := range self.labels {
+		if active.Name == label.Name {
+			perr := NewParseError("label '%s' has already been declared", label.Name)
+			return nil, perr.SetLocation(token.Location)
+		}
+	}
+
+	next, err := self.peekToken()
+	if err != nil {
+		return nil, err
+	}
+	if next == nil {
+		return nil, self.unexpectedToken(next, "LABELED_STATEMENT")
+	}
+
+	kind := ""
+	switch next.Value {
+	case "for", "while", "do":
+		kind = "loop"
+	case "switch":
+		kind = "switch"
+	}
+	// labels directly before this one apply to the same statement
+	for i := len(self.labels) - 1; i >= 0 && self.labels[i].bodyStart == token.Location; i-- {
+		self.labels[i].Kind = kind
+		self.labels[i].bodyStart = next.Location
+	}
+
+	self.labels = append(self.labels, _Label{label.Name, kind, next.Location})
+	node.Body, err = self.parseSubStatement("LABELED_STATEMENT")
+	self.labels = self.labels[:len(self.labels)-1]
+	if err != nil {
+		return nil, err
+	}
+
+	return node, nil
+}
+
+// parses the body of a loop, which break and continue may refer to,
+// and which cannot be a function declaration, even a labelled one
+func (self *Parser) parseLoopBody(context string) (AstNode, error) {
+	start := self.startLocation()
+	self.labels = append(self.labels, _Label{"", "loop", Cursor{}})
+	body, err := self.parseSubStatement(context)
+	self.labels = self.labels[:len(self.labels)-1]
+	if err != nil {
+		return nil, err
+	}
+	if _UnwrapLabels(body).AstType() == FUNCTION_DECLARATION {
+		return nil, NewParseError("function declaration cannot appear as the body of a loop").SetLocation(start)
+	}
+	return body, nil
+}
+
+// the statement a chain of labels applies to
+func _UnwrapLabels(node AstNode) AstNode {
+	for {
+		labeled, ok := node.(*LabeledStatement)
+		if !ok {
+			return node
+		}
+		node = labeled.Body
+	}
+}
+
+// parses while statement
+func (self *Parser) parseWhileStatement() (AstNode, error) {
+	node := new(WhileStatement)
+	node.Type = WHILE_STATEMENT
+
+	_, err := self.expectToken("while", "WHILE_STATEMENT")
+	if err != nil {
+		return nil, err
+	}
+	_, err = self.expectToken("(", "WHILE_STATEMENT")
+	if err != nil {
+		return nil, err
+	}
+
+	node.Test, err = self.parseExpression()
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = self.expectToken(")", "WHILE_STATEMENT")
+	if err != nil {
+		return nil, err
+	}
+
+	node.Body, err = self.parseLoopBody("WHILE_STATEMENT")
+	if err != nil {
+		return nil, err
+	}
+
+	return node, nil
+}
+
+// parses do-while statement
+func (self *Parser) parseDoWhileStatement() (AstNode, error) {
+	node := new(DoWhileStatement)
+	node.Type = DO_WHILE_STATEMENT
+
+	_, err := self.expectToken("do", "DO_WHILE_STATEMENT")
+	if err != nil {
+		return nil, err
+	}
+
+	node.Body, err = self.parseLoopBody("DO_WHILE_STATEMENT")
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = self.expectToken("while", "DO_WHILE_STATEMENT")
+	if err != nil {
+		return nil, err
+	}
+	_, err = self.expectToken("(", "DO_WHILE_STATEMENT")
+	if err != nil {
+		return nil, err
+	}
+
+	node.Test, err = self.parseExpression()
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = self.expectToken(")", "DO_WHILE_STATEMENT")
+	if err != nil {
+		return nil, err
+	}
+
+	// the semicolon after a do-while may always be omitted
+	token, err := self.peekToken()
+	if err != nil {
+		return nil, err
+	}
+	if token != nil && token.Value == ";" {
+		_, _ = self.nextToken()
+	}
+
+	return node, nil
+}
+
+// parses break or continue statement, with an optional label on the same line
+func (self *Parser) parseBreakContinueStatement() (AstNode, error) {
+	keyword, err := self.nextToken()
+	if err != nil {
+		return nil, err
+	}
+
+	var label *Identifier
+	token, err := self.peekTokenOnSameLine()
+	if err != nil {
+		return nil, err
+	}
+	if token != nil && token.Type == ATOM {
+		_, _ = self.nextToken()
+		labelNode, err := self.parseIdentifier(token)
+		if err != nil {
+			return nil, err
+		}
+		label = labelNode.(*Identifier)
+	}
+
+	err = self.checkJumpTarget(keyword, label)
+	if err != nil {
+		return nil, err
+	}
+
+	if keyword.Value == "continue" {
+		node := new(ContinueStatement)
+		node.Type = CONTINUE_STATEMENT
+		if label != nil {
+			node.Label = label
+		}
+		return node, nil
+	}
+	node := new(BreakStatement)
+	node.Type = BREAK_STATEMENT
+	if label != nil {
+		node.Label = label
+	}
+	return node, nil
+}
+
+// checks that break or continue has an enclosing statement to jump to
+func (self *Parser) checkJumpTarget(keyword *Token, label *Identifier) error {
+	isContinue := keyword.Value == "continue"
+	for i := len(self.labels) - 1; i >= 0; i-- {
+		active := self.labels[i]
+		if label == nil {
+			if active.Kind == "loop" || (!isContinue && active.Kind == "switch") {
+				return nil
+			}
+			continue
+		}
+		if active.Name == label.Name {
+			if isContinue && active.Kind != "loop" {
+				perr := NewParseError("illegal continue statement: '%s' does not denote an iteration statement", label.Name)
+				return perr.SetLocation(keyword.Location)
+			}
+			return nil
+		}
+	}
+
+	if label != nil {
+		return NewParseError("undefined label '%s'", label.Name).SetLocation(keyword.Location)
+	}
+	return NewParseError("illegal %s statement", keyword.Value).SetLocation(keyword.Location)
+}
+
+// parses if statement, whose else belongs to the nearest if
+func (self *Parser) parseIfStatement() (AstNode, error) {
+	node := new(IfStatement)
+	node.Type = IF_STATEMENT
+
+	_, err := self.expectToken("if", "IF_STATEMENT")
+	if err != nil {
+		return nil, err
+	}
+	_, err = self.expectToken("(", "IF_STATEMENT")
+	if err != nil {
+		return nil, err
+	}
+
+	node.Test, err = self.parseExpression()
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = self.expectToken(")", "IF_STATEMENT")
+	if err != nil {
+		return nil, err
+	}
+
+	node.Consequent, err = self.parseIfBody()
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := self.peekToken()
+	if err != nil {
+		return nil, err
+	}
+	if token != nil && token.Value == "else" {
+		_, _ = self.nextToken()
+		node.Alternate, err = self.parseIfBody()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return node, nil
+}
+
+// parses a branch of an if statement, which may be a function
+// declaration, but not a labelled one
+func (self *Parser) parseIfBody() (AstNode, error) {
+	start := self.startLocation()
+	body, err := self.parseSubStatement("IF_STATEMENT")
+	if err != nil {
+		return nil, err
+	}
+	if body.AstType() == LABELED_STATEMENT && _UnwrapLabels(body).AstType() == FUNCTION_DECLARATION {
+		return nil, NewParseError("labelled function declaration cannot appear as the body of an if statement").SetLocation(start)
+	}
+	return body, nil
+}
+
+// parses the statement forming the body of another, which must be present
+// and cannot be a lexical declaration
+func (self *Parser) parseSubStatement(context string) (AstNode, error) {
+	token, err := self.peekToken()
+	if err != nil {
+		return nil, err
+	}
+	if token != nil && (token.Value == "const" || token.Value == "class" || (token.Value == "let" && self.isLetDeclaration())) {
+		perr := NewParseError("lexical declaration cannot appear in a single-statement context")
+		return nil, perr.SetLocation(token.Location)
+	}
+
+	statement, err := self.parseStatement()
+	if err != nil {
+		return nil, err
+	}
+	if statement == nil {
+		return nil, self.unexpectedToken(nil, context)
+	}
+	return statement, nil
+}
+
+// parses a for statement, which is a for-in or for-of statement
+// when a single target in its head is followed by in or of
+func (self *Parser) parseForStatement() (AstNode, error) {
+	_, err := self.expectToken("for", "FOR_STATEMENT")
+	if err != nil {
+		return nil, err
+	}
+	token, err := self.peekToken()
+	if err != nil {
+		return nil, err
+	}
+	await := token != nil && token.Value == "await"
+	if await {
+		if !self.inAsync {
+			return nil, NewParseError("for await is only valid in async functions and the top level bodies of modules").SetLocation(token.Location)
+		}
+		_, _ = self.nextToken()
+	}
+	_, err = self.expectToken("(", "FOR_STATEMENT")
+	if err != nil {
+		return nil, err
+	}
+
+	token, err = self.peekToken()
+	if err != nil {
+		return nil, err
+	}
+	if token == nil {
+		return nil, self.unexpectedToken(token, "FOR_STATEMENT")
+	}
+	isLexical := token.Value == "const" || (token.Value == "let" && self.isLetDeclaration())
+	if isLexical {
+		// let and const in the head are scoped to the loop
+		self.enterScope(false)
+		defer self.leaveScope()
+	}
+
+	// in would be taken for the start of a for-in statement
+	var init AstNode
+	start := token.Location
+	inits := len(self.shorthandInits)
+	self.noIn = true
+	switch {
+	case token.Value == ";":
+	case token.Value == "var" || isLexical:
+		init, err = self.parseVariableDeclaration(true)
+		if err == nil {
+			self.finishNode(init, start)
+		}
+	default:
+		// an object literal may turn out to be the target of a for-in or for-of
+		init, err = self.parseMaybePattern()
+		if err == nil {
+			init, err = self.parseSequenceExpression(init, start)
+		}
+	}
+	self.noIn = false
+	if err != nil {
+		return nil, err
+	}
+
+	if init != nil {
+		keyword, err := self.peekToken()
+		if err != nil {
+			return nil, err
+		}
+		if keyword != nil && keyword.Type == ATOM && (keyword.Value == "in" || keyword.Value == "of") {
+			if keyword.Value == "of" && token.Value == "let" && !isLexical {
+				return nil, NewParseError("the left-hand side of a for-of loop may not be 'let'").SetLocation(start)
+			}
+			self.shorthandInits = self.shorthandInits[:inits]
+			return self.parseForInOfStatement(init, start, await)
+		}
+	}
+	err = self.checkShorthandInits(inits)
+	if err != nil {
+		return nil, err
+	}
+	if await {
+		return nil, NewParseError("for await is only valid with for-of loops").SetLocation(start)
+	}
+
+	node := new(ForStatement)
+	node.Type = FOR_STATEMENT
+	node.Init = init
+	_, err = self.expectToken(";", "FOR_STATEMENT")
+	if err != nil {
+		return nil, err
+	}
+
+	node.Test, err = self.parseForHeadExpression(";")
+	if err != nil {
+		return nil, err
+	}
+	node.Update, err = self.parseForHeadExpression(")")
+	if err != nil {
+		return nil, err
+	}
+
+	node.Body, err = self.parseLoopBody("FOR_STATEMENT")
+	if err != nil {
+		return nil, err
+	}
+
+	return node, nil
+}
+
+// finishes parsing a for-in or for-of statement given the declaration
+// or target preceding in or of, which begins at start
+func (self *Parser) parseForInOfStatement(left AstNode, start Cursor, await bool) (AstNode, error) {
+	keyword, err := self.nextToken()
+	if err != nil {
+		return nil, err
+	}
+	loop := "for-" + keyword.Value
+	if await && keyword.Value != "of" {
+		return nil, NewParseError("for await is only valid with for-of loops").SetLocation(start)
+	}
+
+	if declaration, ok := left.(*VariableDeclaration); ok {
+		if len(declaration.Declarations) != 1 {
+			return nil, NewParseError("invalid left-hand side in %s loop: must have a single binding", loop).SetLocation(start)
+		}
+		// legacy code may give a var in a for-in head an initial value
+		declarator := declaration.Declarations[0].(*VariableDeclarator)
+		if declarator.Init != nil && (keyword.Value == "of" || declaration.Kind != "var" || declarator.Id.AstType() != IDENTIFIER) {
+			return nil, NewParseError("%s loop variable declaration may not have an initializer", loop).SetLocation(start)
+		}
+	} else {
+		left, err = self.toAssignmentTarget(left, start)
+		if err != nil {
+			return nil, err
+		}
+		if left == nil {
+			return nil, NewParseError("invalid left-hand side in %s loop", loop).SetLocation(start)
+		}
+	}
+
+	var right AstNode
+	if keyword.Value == "in" {
+		right, err = self.parseExpression()
+	} else {
+		right, err = self.parseMaybeAssignment()
+	}
+	if err != nil {
+		return nil, err
+	}
+	_, err = self.expectToken(")", "FOR_STATEMENT")
+	if err != nil {
+		return nil, err
+	}
+
+	if keyword.Value == "in" {
+		node := new(ForInStatement)
+		node.Type = FOR_IN_STATEMENT
+		node.Left = left
+		node.Right = right
+		node.Body, err = self.parseLoopBody("FOR_IN_STATEMENT")
+		if err != nil {
+			return nil, err
+		}
+		return node, nil
+	}
+
+	node := new(ForOfStatement)
+	node.Type = FOR_OF_STATEMENT
+	node.Left = left
+	node.Right = right
+	node.Await = await
+	node.Body, err = self.parseLoopBody("FOR_OF_STATEMENT")
+	if err != nil {
+		return nil, err
+	}
+	return node, nil
+}
+
+// the name of a property key that is not computed, or empty for numbers
+func _PropertyKeyName(key AstNode) string {
+	switch key := key.(type) {
+	case *Identifier:
+		return key.Name
+	case *LiteralString:
+		return key.Value
+	case *PrivateIdentifier:
+		return "#" + key.Name
+	}
+	return ""
+}
+
+// appends the names bound by an identifier or pattern
+func _CollectBoundNames(node AstNode, names []string) []string {
+	switch node := node.(type) {
+	case *Identifier:
+		names = append(names, node.Name)
+	case *ArrayPattern:
+		for _, element := range node.Elements {
+			if element != nil {
+				names = _CollectBoundNames(element, names)
+			}
+		}
+	case *ObjectPattern:
+		for _, property := range node.Properties {
+			if property, ok := property.(*Property); ok {
+				names = _CollectBoundNames(property.Value, names)
+			} else {
+				names = _CollectBoundNames(property, names)
+			}
+		}
+	case *AssignmentPattern:
+		names = _CollectBoundNames(node.Left, names)
+	case *RestElement:
+		names = _CollectBoundNames(node.Argument, names)
+	}
+	return names
+}
+
+// whether an expression may be assigned to directly
+func _IsSimpleAssignmentTarget(node AstNode) bool {
+	switch node.AstType() {
+	case IDENTIFIER, MEMBER_EXPRESSION:
+		return true
+	}
+	return false
+}
+
+// parses the optional test or update of a for statement, and the token ending it
+func (self *Parser) parseForHeadExpression(end string) (AstNode, error) {
+	token, err := self.peekToken()
+	if err != nil {
+		return nil, err
+	}
+
+	var node AstNode
+	if token != nil && token.Value != end {
+		node, err = self.parseExpression()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	_, err = self.expectToken(end, "FOR_STATEMENT")
+	if err != nil {
+		return nil, err
+	}
+	return node, nil
+}
+
+// parses return statement
+func (self *Parser) parseReturnStatement() (AstNode, error) {
+	_, err := self.expectToken("return", "RETURN_STATEMENT")
+	if err != nil {
+		return nil, err
+	}
+
+	node := new(ReturnStatement)
+	node.Type = RETURN_STATEMENT
+
+	// a line break ends the statement, so return\nx returns nothing
+	token, err := self.peekTokenOnSameLine()
+	if err != nil {
+		return nil, err
+	}
+	if token == nil || token.Value == ";" || token.Value == "}" {
+		return node, nil
+	}
+
+	node.Argument, err = self.parseExpression()
+	if err != nil {
+		return nil, err
+	}
+	return node, nil
+}
+
+// parses switch statement
+func (self *Parser) parseSwitchStatement() (AstNode, error) {
+	node := new(SwitchStatement)
+	node.Type = SWITCH_STATEMENT
+	node.Cases = []AstNode{}
+
+	_, err := self.expectToken("switch", "SWITCH_STATEMENT")
+	if err != nil {
+		return nil, err
+	}
+	_, err = self.expectToken("(", "SWITCH_STATEMENT")
+	if err != nil {
+		return nil, err
+	}
+
+	node.Discriminant, err = self.parseExpression()
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = self.expectToken(")", "SWITCH_STATEMENT")
+	if err != nil {
+		return nil, err
+	}
+	_, err = self.expectToken("{", "SWITCH_STATEMENT")
+	if err != nil {
+		return nil, err
+	}
+
+	self.labels = append(self.labels, _Label{"", "switch", Cursor{}})
+	defer func() { self.labels = self.labels[:len(self.labels)-1] }()
+	// all cases share one scope
+	self.enterScope(false)
+	defer self.leaveScope()
+
+	hasDefault := false
+	for {
+		token, err := self.nextToken()
+		if err != nil {
+			return nil, err
+		}
+		if token != nil && token.Value == "}" {
+			return node, nil
+		}
+		if token == nil || (token.Value != "case" && token.Value != "default") {
+			return nil, self.unexpectedToken(token, "SWITCH_STATEMENT")
+		}
+
+		caseNode := new(SwitchCase)
+		caseNode.Type = SWITCH_CASE
+		caseNode.Consequent = []AstNode{}
+
+		if token.Value == "default" {
+			if hasDefault {
+				perr := NewParseError("more than one default clause in switch statement")
+				return nil, perr.SetLocation(token.Location)
+			}
+			hasDefault = true
+		} else {
+			caseNode.Test, err = self.parseExpression()
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		_, err = self.expectToken(":", "SWITCH_CASE")
+		if err != nil {
+			return nil, err
+		}
+
+		for {
+			next, err := self.peekToken()
+			if err != nil {
+				return nil, err
+			}
+			if next == nil {
+				return nil, self.unexpectedToken(next, "SWITCH_CASE")
+			}
+			if next.Value == "case" || next.Value == "default" || next.Value == "}" {
+				break
+			}
+
+			statement, err := self.parseStatement()
+			if err != nil {
+				return nil, err
+			}
+			caseNode.Consequent = append(caseNode.Consequent, statement)
+		}
+
+		node.Cases = append(node.Cases, self.finishNode(caseNode, token.Location))
+	}
+}
+
+// parses throw statement, whose argument must start on the same line
+func (self *Parser) parseThrowStatement() (AstNode, error) {
+	token, err := self.expectToken("throw", "THROW_STATEMENT")
+	if err != nil {
+		return nil, err
+	}
+
+	node := new(ThrowStatement)
+	node.Type = THROW_STATEMENT
+
+	next, err := self.peekTokenOnSameLine()
+	if err != nil {
+		return nil, err
+	}
+	if next == nil {
+		next, err = self.peekToken()
+		if err != nil {
+			return nil, err
+		}
+		if next != nil {
+			return nil, NewParseError("illegal newline after throw").SetLocation(token.Location)
+		}
+		return nil, self.unexpectedToken(next, "THROW_STATEMENT")
+	}
+
+	node.Argument, err = self.parseExpression()
+	if err != nil {
+		return nil, err
+	}
+	return node, nil
+}
+
+// parses try statement, with a catch clause, a finally block, or both
+func (self *Parser) parseTryStatement() (AstNode, error) {
+	token, err := self.expectToken("try", "TRY_STATEMENT")
+	if err != nil {
+		return nil, err
+	}
+
+	node := new(TryStatement)
+	node.Type = TRY_STATEMENT
+
+	node.Block, err = self.parseBlockStatement()
+	if err != nil {
+		return nil, err
+	}
+
+	next, err := self.peekToken()
+	if err != nil {
+		return nil, err
+	}
+	if next != nil && next.Value == "catch" {
+		_, _ = self.nextToken()
+		node.Handler, err = self.parseCatchClause(next)
+		if err != nil {
+			return nil, err
+		}
+
+		next, err = self.peekToken()
+		if err != nil {
+			return nil, err
+		}
+	}
+	if next != nil && next.Value == "finally" {
+		_, _ = self.nextToken()
+		node.Finalizer, err = self.parseBlockStatement()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if node.Handler == nil && node.Finalizer == nil {
+		return nil, NewParseError("missing catch or finally after try").SetLocation(token.Location)
+	}
+	return node, nil
+}
+
+// finishes parsing a catch clause, whose binding may be omitted
+func (self *Parser) parseCatchClause(token *Token) (AstNode, error) {
+	node := new(CatchClause)
+	node.Type = CATCH_CLAUSE
+
+	// the param shares the scope of the body
+	scope := self.enterScope(false)
+	defer self.leaveScope()
+
+	next, err := self.peekToken()
+	if err != nil {
+		return nil, err
+	}
+	if next != nil && next.Value == "(" {
+		_, _ = self.nextToken()
+		names := []*Token{}
+		node.Param, err = self.parseBindingTarget(&names)
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range names {
+			if scope.lexical[name.Value] {
+				return nil, self.redeclarationError(name.Value, name.Location)
+			}
+			scope.lexical[name.Value] = true
+		}
+		if node.Param.AstType() == IDENTIFIER {
+			scope.catchParam = names[0].Value
+		}
+		_, err = self.expectToken(")", "CATCH_CLAUSE")
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	node.Body, err = self.parseBlock()
+	if err != nil {
+		return nil, err
+	}
+
+	return self.finishNode(node, token.Location), nil
+}
+
+// parses a function declaration into a statement node,
+// whose name may be left out when anonymous, as after export default
+func (self *Parser) parseFunctionDeclaration(anonymous bool) (AstNode, error) {
+	node := new(FunctionDeclaration)
+	node.Type = FUNCTION_DECLARATION
+
+	token, err := self.nextToken()
+	if err != nil {
+		return nil, err
+	}
+	if token != nil && token.Value == "async" {
+		node.Async = true
+		token, err = self.nextToken()
+		if err != nil {
+			return nil, err
+		}
+	}
+	if token == nil || token.Value != "function" {
+		return nil, self.unexpectedToken(token, "FUNCTION_DECLARATION")
+	}
+
+	token, err = self.nextToken()
+	if err != nil {
+		return nil, err
+	}
+	if token != nil && token.Type == OPERATOR && token.Value == "*" {
+		node.Generator = true
+		token, err = self.nextToken()
+		if err != nil {
+			return nil, err
+		}
+	}
+	if anonymous && token != nil && token.Value == "(" {
+		self.unreadToken()
+	} else {
+		if token == nil || token.Type != ATOM {
+			return nil, self.unexpectedToken(token, "FUNCTION_DECLARATION")
+		}
+		node.Id, err = self.parseIdentifier(token)
+		if err != nil {
+			return nil, err
+		}
+		err = self.declareFunction(token.Value, token.Location)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	_, err = self.expectToken("(", "FUNCTION_DECLARATION")
+	if err != nil {
+		return nil, err
+	}
+	defer self.allowSuper(false, false)()
+	defer self.allowAwaitYield(node.Async, node.Generator)()
+	node.Params, err = self.parseParamList()
+	if err != nil {
+		return nil, err
+	}
+
+	captureStart := self.scanner.Location
+	self.scanner.BeginCapture()
+	node.Body, err = self.parseFunctionBody(node.Params)
+	capture := self.scanner.FinishCapture()
+	if err != nil {
+		return nil, err
+	}
+	// the token after the body may have been read, to find comments following it
+	node.Source = _TrimFunctionSource(capture.Prefix(self.lastEnd.Offset - captureStart.Offset))
+	node.Params, node.Defaults, node.Rest = self.splitParams(node.Params)
+
+	return node, nil
+}
+
+// separates the defaults and rest param from params when LegacyParams is set,
+// with null defaults for the params without one
+func (self *Parser) splitParams(params []AstNode) ([]AstNode, []AstNode, AstNode) {
+	defaults := []AstNode{}
+	if !self.LegacyParams {
+		return params, defaults, nil
+	}
+
+	plain := []AstNode{}
+	var rest AstNode
+	hasDefault := false
+	for _, param := range params {
+		switch param := param.(type) {
+		case *AssignmentPattern:
+			plain = append(plain, param.Left)
+			defaults = append(defaults, param.Right)
+			hasDefault = true
+		case *RestElement:
+			rest = param.Argument
+		default:
+			plain = append(plain, param)
+			defaults = append(defaults, nil)
+		}
+	}
+	if !hasDefault {
+		defaults = []AstNode{}
+	}
+	return plain, defaults, rest
+}
+
+// parses the block of a function, which labels and loops outside it do not reach into
+// the params share the scope of the block
+func (self *Parser) parseFunctionBody(params []AstNode) (AstNode, error) {
+	defer self.enterFunction(params)()
+	return self.parseBlock()
+}
+
+// begins the scope of a function body, declaring its params,
+// returning a function that ends it
+func (self *Parser) enterFunction(params []AstNode) func() {
+	labels := self.labels
+	self.labels = nil
+
+	self.enterScope(true)
+	for _, param := range params {
+		for _, name := range _CollectBoundNames(param, nil) {
+			self.currentScope().vars[name] = true
+		}
+	}
+
+	return func() {
+		self.leaveScope()
+		self.labels = labels
+	}
+}
+
+// parses a var, let or const declaration
+// const declarations in the head of a for statement may be completed by in or of
+func (self *Parser) parseVariableDeclaration(inForHead bool) (AstNode, error) {
+	node := new(VariableDeclaration)
+	node.Type = VARIABLE_DECLARATION
+	node.Declarations = []AstNode{}
+
+	token, err := self.nextToken()
+	if err != nil {
+		return nil, err
+	}
+	if token == nil || (token.Value != "var" && token.Value != "let" && token.Value != "const") {
+		return nil, self.unexpectedToken(token, "VARIABLE_DECLARATION")
+	}
+	node.Kind = token.Value
+
+	for {
+		start := self.startLocation()
+		declNode := new(VariableDeclarator)
+		declNode.Type = VARIABLE_DECLARATOR
+		names := []*Token{}
+		declNode.Id, err = self.parseBindingTarget(&names)
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range names {
+			if node.Kind == "var" {
+				err = self.declareVar(name.Value, name.Location)
+			} else {
+				err = self.declareLexical(name.Value, name.Location)
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		next, err := self.peekToken()
+		if err != nil {
+			return nil, err
+		}
+		if next != nil && next.Value == "=" {
+			_, _ = self.nextToken()
+			declNode.Init, err = self.parseMaybeAssignment()
+			if err != nil {
+				return nil, err
+			}
+		} else if !(inForHead && next != nil && (next.Value == "in" || next.Value == "of")) {
+			if declNode.Id.AstType() != IDENTIFIER {
+				return nil, NewParseError("missing initializer in destructuring declaration").SetLocation(start)
+			}
+			if node.Kind == "const" {
+				return nil, NewParseError("missing initializer in const declaration").SetLocation(start)
+			}
+		}
+		node.Declarations = append(node.Declarations, self.finishNode(declNode, start))
+
+		next, err = self.peekToken()
+		if err != nil {
+			return nil, err
+		}
+		if next == nil || next.Value != "," {
+			return node, nil
+		}
+		_, _ = self.nextToken()
+	}
+}
+
+// parses an identifier or a destructuring pattern that binds names,
+// adding the token of each bound name to names
+func (self *Parser) parseBindingTarget(names *[]*Token) (AstNode, error) {
+	err := self.enterNesting()
+	defer self.leaveNesting()
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := self.nextToken()
+	if err != nil {
+		return nil, err
+	}
+	if token == nil {
+		return nil, self.unexpectedToken(token, "PATTERN")
+	}
+
+	switch {
+	case token.Value == "[":
+		return self.parseArrayPattern(token, names)
+	case token.Value == "{":
+		return self.parseObjectPattern(token, names)
+	case token.Type == ATOM:
+		err = self.checkIdentifierName(token)
+		if err != nil {
+			return nil, err
+		}
+		*names = append(*names, token)
+		return self.parseIdentifier(token)
+	}
+	return nil, self.unexpectedToken(token, "PATTERN")
+}
+
+// parses a binding target followed by an optional default value
+func (self *Parser) parseBindingElement(names *[]*Token) (AstNode, error) {
+	start := self.startLocation()
+	target, err := self.parseBindingTarget(names)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := self.peekToken()
+	if err != nil {
+		return nil, err
+	}
+	if token == nil || token.Value != "=" {
+		return target, nil
+	}
+	_, _ = self.nextToken()
+
+	node := new(AssignmentPattern)
+	node.Type = ASSIGNMENT_PATTERN
+	node.Left = target
+	node.Right, err = self.parseMaybeAssignment()
+	if err != nil {
+		return nil, err
+	}
+	return self.finishNode(node, start), nil
+}
+
+// finishes parsing an array pattern, where elements may be skipped
+func (self *Parser) parseArrayPattern(token *Token, names *[]*Token) (AstNode, error) {
+	node := new(ArrayPattern)
+	node.Type = ARRAY_PATTERN
+	node.Elements = []AstNode{}
+
+	for {
+		next, err := self.peekToken()
+		if err != nil {
+			return nil, err
+		}
+		if next == nil {
+			return nil, self.unexpectedToken(next, "ARRAY_PATTERN")
+		}
+
+		switch next.Value {
+		case "]":
+			_, _ = self.nextToken()
+			return self.finishNode(node, token.Location), nil
+		case ",":
+			// elision
+			_, _ = self.nextToken()
+			node.Elements = append(node.Elements, nil)
+			continue
+		case "...":
+			rest, err := self.parseRestElement(names)
+			if err != nil {
+				return nil, err
+			}
+			node.Elements = append(node.Elements, rest)
+			err = self.expectPatternEnd("]")
+			if err != nil {
+				return nil, err
+			}
+			return self.finishNode(node, token.Location), nil
+		}
+
+		element, err := self.parseBindingElement(names)
+		if err != nil {
+			return nil, err
+		}
+		node.Elements = append(node.Elements, element)
+
+		next, err = self.nextToken()
+		if err != nil {
+			return nil, err
+		}
+		if next == nil || (next.Value != "]" && next.Value != ",") {
+			return nil, self.unexpectedToken(next, "ARRAY_PATTERN")
+		}
+		if next.Value == "]" {
+			return self.finishNode(node, token.Location), nil
+		}
+	}
+}
+
+// finishes parsing an object pattern, with shorthand properties binding their own keys
+func (self *Parser) parseObjectPattern(token *Token, names *[]*Token) (AstNode, error) {
+	node := new(ObjectPattern)
+	node.Type = OBJECT_PATTERN
+	node.Properties = []AstNode{}
+
+	for {
+		next, err := self.nextToken()
+		if err != nil {
+			return nil, err
+		}
+		if next == nil {
+			return nil, self.unexpectedToken(next, "OBJECT_PATTERN")
+		}
+		if next.Value == "}" {
+			return self.finishNode(node, token.Location), nil
+		}
+
+		if next.Value == "..." {
+			self.unreadToken()
+			rest, err := self.parseRestElement(names)
+			if err != nil {
+				return nil, err
+			}
+			if rest.(*RestElement).Argument.AstType() != IDENTIFIER {
+				return nil, NewParseError("rest property must be followed by an identifier").SetLocation(next.Location)
+			}
+			node.Properties = append(node.Properties, rest)
+			err = self.expectPatternEnd("}")
+			if err != nil {
+				return nil, err
+			}
+			return self.finishNode(node, token.Location), nil
+		}
+
+		property, err := self.parsePatternProperty(next, names)
+		if err != nil {
+			return nil, err
+		}
+		node.Properties = append(node.Properties, property)
+
+		next, err = self.peekToken()
+		if err != nil {
+			return nil, err
+		}
+		if next == nil || (next.Value != "}" && next.Value != ",") {
+			return nil, self.unexpectedToken(next, "OBJECT_PATTERN")
+		}
+		if next.Value == "," {
+			_, _ = self.nextToken()
+		}
+	}
+}
+
+// finishes parsing a property of an object pattern given its key
+func (self *Parser) parsePatternProperty(token *Token, names *[]*Token) (AstNode, error) {
+	node := new(Property)
+	node.Type = PROPERTY
+	node.Kind = "init"
+
+	var err error
+	node.Key, node.Computed, err = self.parsePropertyKey(token, "OBJECT_PATTERN")
+	if err != nil {
+		return nil, err
+	}
+	if token.Type == PRIVATE_NAME {
+		return nil, self.unexpectedToken(token, "OBJECT_PATTERN")
+	}
+
+	next, err := self.peekToken()
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case next != nil && next.Value == ":":
+		_, _ = self.nextToken()
+		node.Value, err = self.parseBindingElement(names)
+		if err != nil {
+			return nil, err
+		}
+	case token.Type == ATOM && !node.Computed:
+		// shorthand, binding the key
+		err = self.checkIdentifierName(token)
+		if err != nil {
+			return nil, err
+		}
+		*names = append(*names, token)
+		node.Shorthand = true
+		node.Value = node.Key
+		if next != nil && next.Value == "=" {
+			_, _ = self.nextToken()
+			value := new(AssignmentPattern)
+			value.Type = ASSIGNMENT_PATTERN
+			value.Left = node.Key
+			value.Right, err = self.parseMaybeAssignment()
+			if err != nil {
+				return nil, err
+			}
+			node.Value = self.finishNode(value, token.Location)
+		}
+	default:
+		return nil, self.unexpectedToken(next, "OBJECT_PATTERN")
+	}
+
+	return self.finishNode(node, token.Location), nil
+}
+
+// parses a rest element, which gathers the remaining elements or properties
+func (self *Parser) parseRestElement(names *[]*Token) (AstNode, error) {
+	token, err := self.expectToken("...", "REST_ELEMENT")
+	if err != nil {
+		return nil, err
+	}
+
+	node := new(RestElement)
+	node.Type = REST_ELEMENT
+	node.Argument, err = self.parseBindingTarget(names)
+	if err != nil {
+		return nil, err
+	}
+	return self.finishNode(node, token.Location), nil
+}
+
+// reads the bracket closing a pattern after a rest element
+func (self *Parser) expectPatternEnd(end string) error {
+	token, err := self.nextToken()
+	if err != nil {
+		return err
+	}
+	if token != nil && token.Value == "," {
+		return NewParseError("rest element must be last element").SetLocation(token.Location)
+	}
+	if token == nil || token.Value != end {
+		return self.unexpectedToken(token, "PATTERN")
+	}
+	return nil
+}
+
+// reinterprets the left side of an assignment as its target,
+// or returns nil if it cannot be assigned to
+func (self *Parser) toAssignmentTarget(node AstNode, location Cursor) (AstNode, error) {
+	switch node.AstType() {
+	case ARRAY_EXPRESSION, OBJECT_EXPRESSION:
+		return self.toPattern(node, false, location)
+	}
+	if _IsSimpleAssignmentTarget(node) {
+		return node, nil
+	}
+	return nil, nil
+}
+
+// reinterprets an expression as a pattern, where binding
+// patterns only bind identifiers rather than assign to members
+func (self *Parser) toPattern(node AstNode, binding bool, location Cursor) (AstNode, error) {
+	switch expression := node.(type) {
+	case *Identifier:
+		return node, nil
+	case *ArrayPattern, *ObjectPattern, *AssignmentPattern, *RestElement:
+		// converted when their own assignment was read, but may
+		// now have to bind names rather than assign to members
+		if binding {
+			return node, self.checkBindingPattern(node, location)
+		}
+		return node, nil
+	case *MemberExpression:
+		if !binding {
+			return node, nil
+		}
+	case *ArrayExpression:
+		pattern := new(ArrayPattern)
+		pattern.AstNodeMeta = expression.AstNodeMeta
+		pattern.Type = ARRAY_PATTERN
+		pattern.Elements = []AstNode{}
+		for i, element := range expression.Elements {
+			var err error
+			if spread, ok := element.(*SpreadElement); ok {
+				if i != len(expression.Elements)-1 {
+					return nil, NewParseError("rest element must be last element").SetLocation(location)
+				}
+				element, err = self.toRestElement(spread, binding, location)
+			} else if element != nil {
+				element, err = self.toPatternElement(element, binding, location)
+			}
+			if err != nil {
+				return nil, err
+			}
+			pattern.Elements = append(pattern.Elements, element)
+		}
+		return pattern, nil
+	case *ObjectExpression:
+		pattern := new(ObjectPattern)
+		pattern.AstNodeMeta = expression.AstNodeMeta
+		pattern.Type = OBJECT_PATTERN
+		pattern.Properties = []AstNode{}
+		for i, property := range expression.Properties {
+			if spread, ok := property.(*SpreadElement); ok {
+				if i != len(expression.Properties)-1 {
+					return nil, NewParseError("rest element must be last element").SetLocation(location)
+				}
+				if !_IsSimpleAssignmentTarget(spread.Argument) || (binding && spread.Argument.AstType() != IDENTIFIER) {
+					return nil, NewParseError("rest property must be followed by an identifier").SetLocation(location)
+				}
+				rest, err := self.toRestElement(spread, binding, location)
+				if err != nil {
 					return nil, err
 				}
+				pattern.Properties = append(pattern.Properties, rest)
 				continue
 			}
+			property, ok := property.(*Property)
+			if !ok || property.Kind != "init" || property.Method {
+				return nil, NewParseError("invalid destructuring assignment target").SetLocation(location)
+			}
+			value, err := self.toPatternElement(property.Value, binding, location)
+			if err != nil {
+				return nil, err
+			}
+			property.Value = value
+			pattern.Properties = append(pattern.Properties, property)
+		}
+		return pattern, nil
+	}
+	return nil, NewParseError("invalid destructuring assignment target").SetLocation(location)
+}
+
+// checks a pattern converted for assignment binds only identifiers
+func (self *Parser) checkBindingPattern(node AstNode, location Cursor) error {
+	var err error
+	switch node := node.(type) {
+	case *ArrayPattern:
+		for _, element := range node.Elements {
+			if element != nil && err == nil {
+				_, err = self.toPattern(element, true, location)
+			}
+		}
+	case *ObjectPattern:
+		for _, property := range node.Properties {
+			if property, ok := property.(*Property); ok && err == nil {
+				_, err = self.toPattern(property.Value, true, location)
+			}
+		}
+	case *AssignmentPattern:
+		_, err = self.toPattern(node.Left, true, location)
+	case *RestElement:
+		_, err = self.toPattern(node.Argument, true, location)
+	}
+	return err
+}
+
+// records that a comma followed a spread element, so it cannot be a rest element
+func (self *Parser) markSpreadComma(node AstNode) {
+	if self.spreadCommas == nil {
+		self.spreadCommas = map[*SpreadElement]bool{}
+	}
+	self.spreadCommas[node.(*SpreadElement)] = true
+}
+
+// reinterprets a spread element as the rest element of a pattern
+func (self *Parser) toRestElement(spread *SpreadElement, binding bool, location Cursor) (AstNode, error) {
+	if self.spreadCommas[spread] {
+		return nil, NewParseError("rest element must be last element").SetLocation(location)
+	}
+	rest := new(RestElement)
+	rest.AstNodeMeta = spread.AstNodeMeta
+	rest.Type = REST_ELEMENT
+	var err error
+	rest.Argument, err = self.toPattern(spread.Argument, binding, location)
+	if err != nil {
+		return nil, err
+	}
+	return rest, nil
+}
+
+// reinterprets an element of an array or object as a pattern, which may have a default value
+func (self *Parser) toPatternElement(node AstNode, binding bool, location Cursor) (AstNode, error) {
+	assignment, ok := node.(*AssignmentExpression)
+	if !ok || assignment.Operator != "=" {
+		return self.toPattern(node, binding, location)
+	}
+
+	pattern := new(AssignmentPattern)
+	pattern.AstNodeMeta = assignment.AstNodeMeta
+	pattern.Type = ASSIGNMENT_PATTERN
+	var err error
+	pattern.Left, err = self.toPattern(assignment.Left, binding, location)
+	if err != nil {
+		return nil, err
+	}
+	pattern.Right = assignment.Right
+	return pattern, nil
+}
+
+// whether async at the next token begins an async function, rather than an expression
+func (self *Parser) isAsyncFunction() bool {
+	token, err := self.nextToken()
+	if err != nil || token == nil || token.Value != "async" {
+		if token != nil {
+			self.unreadToken()
+		}
+		return false
+	}
+	next, err := self.peekTokenOnSameLine()
+	self.unreadToken()
+	return err == nil && next != nil && next.Value == "function"
+}
+
+// reports await or yield used as an identifier where it is an operator or reserved
+func (self *Parser) checkIdentifierName(token *Token) error {
+	reserved := false
+	switch token.Value {
+	case "await":
+		reserved = self.inAsync || self.inStaticBlock || self.SourceType == "module"
+	case "yield":
+		reserved = self.inGenerator
+	case "arguments":
+		reserved = self.noArguments
+	}
+	if reserved {
+		return NewParseError("'%s' may not be used as an identifier here", token.Value).SetLocation(token.Location)
+	}
+	return nil
+}
+
+// whether let at the next token begins a declaration, rather than being an identifier
+func (self *Parser) isLetDeclaration() bool {
+	token, err := self.nextToken()
+	if err != nil || token == nil || token.Value != "let" {
+		if token != nil {
+			self.unreadToken()
+		}
+		return false
+	}
+	next, err := self.peekToken()
+	self.unreadToken()
+	if err != nil || next == nil {
+		return false
+	}
+
+	switch {
+	case next.Value == "[", next.Value == "{":
+		return true
+	case next.Type == ATOM:
+		return next.Value != "in" && next.Value != "instanceof"
+	}
+	return false
+}
+
+// parses a list of param patterns
+func (self *Parser) parseParamList() ([]AstNode, error) {
+	paramList := []AstNode{}
+	names := []*Token{}
+	simple := true
+
+	inParams := self.inParams
+	self.inParams = true
+	defer func() {
+		self.inParams = inParams
+	}()
+
+	for {
+		token, err := self.peekToken()
+		if err != nil {
+			return nil, err
+		}
+		if token == nil {
+			return nil, self.unexpectedToken(token, "PARAM_LIST")
+		}
+		if token.Value == ")" {
+			_, _ = self.nextToken()
+			break
+		}
+
+		var pNode AstNode
+		if token.Value == "..." {
+			pNode, err = self.parseRestElement(&names)
+		} else {
+			pNode, err = self.parseBindingElement(&names)
+		}
+		if err != nil {
+			return nil, err
+		}
+		simple = simple && pNode.AstType() == IDENTIFIER
+		paramList = append(paramList, pNode)
+
+		token, err = self.nextToken()
+		if err != nil {
+			return nil, err
+		}
+		if token == nil {
+			return nil, self.unexpectedToken(token, "PARAM_LIST")
+		}
+		if token.Value == ")" {
+			break
+		}
+		if pNode.AstType() == REST_ELEMENT {
+			return nil, NewParseError("rest parameter must be last formal parameter").SetLocation(token.Location)
+		}
+		if token.Value != "," {
+			return nil, self.unexpectedToken(token, "PARAM_LIST")
+		}
+	}
+
+	// only plain lists of identifiers may repeat a name
+	if !simple {
+		seen := map[string]bool{}
+		for _, name := range names {
+			if seen[name.Value] {
+				return nil, NewParseError("duplicate parameter name not allowed in this context").SetLocation(name.Location)
+			}
+			seen[name.Value] = true
+		}
+	}
+	return paramList, nil
+}
+
+// parses a list of expressions seperated by commas
+func (self *Parser) parseArgumentList() ([]AstNode, error) {
+	nodeList := []AstNode{}
+	for {
+		token, err := self.peekToken()
+		if err != nil {
+			return nil, err
+		}
+		if token == nil {
+			return nil, self.unexpectedToken(token, "ARGUMENT_LIST")
+		}
+		if token.Value == ")" {
+			_, _ = self.nextToken()
+			break
+		}
+
+		var nextNode AstNode
+		if token.Value == "..." {
+			nextNode, err = self.parseSpreadElement()
+		} else {
+			nextNode, err = self.parseMaybePattern()
+		}
+		if err != nil {
+			return nil, err
+		}
+		nodeList = append(nodeList, nextNode)
+
+		token, err = self.nextToken()
+		if err != nil {
+			return nil, err
+		}
+		if token == nil {
+			return nil, self.unexpectedToken(token, "ARGUMENT_LIST")
+		}
+		if token.Value == ")" {
+			break
+		}
+		if token.Value != "," {
+			return nil, self.unexpectedToken(token, "ARGUMENT_LIST")
+		}
+	}
+	return nodeList, nil
+}
+
+// parses from the start of an expression, which may be a sequence separated by commas
+func (self *Parser) parseExpression() (AstNode, error) {
+	start := self.startLocation()
+	node, err := self.parseMaybeAssignment()
+	if err != nil {
+		return nil, err
+	}
+	return self.parseSequenceExpression(node, start)
+}
+
+// finishes parsing a sequence expression beginning at start,
+// or returns the first expression if no comma follows it
+func (self *Parser) parseSequenceExpression(first AstNode, start Cursor) (AstNode, error) {
+	token, err := self.peekToken()
+	if err != nil {
+		return nil, err
+	}
+	if token == nil || token.Value != "," {
+		return first, nil
+	}
+
+	node := new(SequenceExpression)
+	node.Type = SEQUENCE_EXPRESSION
+	node.Expressions = []AstNode{first}
+	for token != nil && token.Value == "," {
+		_, _ = self.nextToken()
+		expression, err := self.parseMaybeAssignment()
+		if err != nil {
+			return nil, err
+		}
+		node.Expressions = append(node.Expressions, expression)
+
+		token, err = self.peekToken()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return self.finishNode(node, start), nil
+}
+
+// sets whether super calls and super properties may appear in a function,
+// where arguments may appear again, returning a function that puts back the previous rules
+func (self *Parser) allowSuper(call bool, property bool) func() {
+	superCall, superProperty, noArguments := self.superCall, self.superProperty, self.noArguments
+	self.superCall, self.superProperty, self.noArguments = call, property, false
+	return func() {
+		self.superCall, self.superProperty, self.noArguments = superCall, superProperty, noArguments
+	}
+}
+
+// sets whether await and yield are operators in the function being parsed,
+// returning a function that puts back the previous rules
+func (self *Parser) allowAwaitYield(async bool, generator bool) func() {
+	inAsync, inGenerator, inParams, inStaticBlock := self.inAsync, self.inGenerator, self.inParams, self.inStaticBlock
+	self.inAsync, self.inGenerator, self.inParams, self.inStaticBlock = async, generator, false, false
+	return func() {
+		self.inAsync, self.inGenerator, self.inParams, self.inStaticBlock = inAsync, inGenerator, inParams, inStaticBlock
+	}
+}
+
+// lifts the restriction on the in operator inside brackets,
+// returning a function that puts back the previous rule
+func (self *Parser) allowIn() func() {
+	noIn := self.noIn
+	self.noIn = false
+	return func() {
+		self.noIn = noIn
+	}
+}
+
+// parses an assignment expression, or an expression of higher precedence
+func (self *Parser) parseMaybeAssignment() (AstNode, error) {
+	inits := len(self.shorthandInits)
+	node, err := self.parseMaybePattern()
+	if err != nil {
+		return nil, err
+	}
+	err = self.checkShorthandInits(inits)
+	if err != nil {
+		return nil, err
+	}
+	return node, nil
+}
+
+// parses an assignment expression that may yet be reinterpreted as a pattern,
+// such as an element of an array literal, leaving shorthand initializers unchecked
+func (self *Parser) parseMaybePattern() (AstNode, error) {
+	err := self.enterNesting()
+	defer self.leaveNesting()
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := self.peekToken()
+	if err != nil {
+		return nil, err
+	}
+	if token != nil && token.Type == ATOM && token.Value == "yield" && self.inGenerator {
+		_, _ = self.nextToken()
+		return self.parseYieldExpression(token)
+	}
+
+	inits := len(self.shorthandInits)
+	start := self.startLocation()
+	left, err := self.parseMaybeBinary(0)
+	if err != nil {
+		return nil, err
+	}
+	if head, ok := left.(*_ArrowHead); ok {
+		self.shorthandInits = self.shorthandInits[:inits]
+		return self.parseArrowFunction(head, start)
+	}
+
+	token, err = self.peekToken()
+	if err != nil {
+		return nil, err
+	}
+	if token != nil && token.Type == OPERATOR && token.Value == "?" {
+		_, _ = self.nextToken()
+		return self.parseConditionalExpression(left, start)
+	}
+	if token != nil && IsAssignmentOperator(token) {
+		target := left
+		if token.Value == "=" {
+			target, err = self.toAssignmentTarget(left, start)
+		} else if !_IsSimpleAssignmentTarget(left) {
+			target = nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if target == nil {
+			return nil, NewParseError("invalid left-hand side in assignment").SetLocation(start)
+		}
+		self.shorthandInits = self.shorthandInits[:inits]
+		node, err := self.parseAssignmentExpression(target)
+		if err != nil {
+			return nil, err
+		}
+		return self.finishNode(node, start), nil
+	}
+	return left, nil
+}
+
+// finishes parsing a conditional expression after its question mark,
+// given its test beginning at start
+func (self *Parser) parseConditionalExpression(test AstNode, start Cursor) (AstNode, error) {
+	node := new(ConditionalExpression)
+	node.Type = CONDITIONAL_EXPRESSION
+	node.Test = test
+
+	// in is allowed before the colon, even in the head of a for statement
+	var err error
+	restoreIn := self.allowIn()
+	node.Consequent, err = self.parseMaybeAssignment()
+	restoreIn()
+	if err != nil {
+		return nil, err
+	}
+	_, err = self.expectToken(":", "CONDITIONAL_EXPRESSION")
+	if err != nil {
+		return nil, err
+	}
+	// right associative, so the alternate may itself be conditional
+	node.Alternate, err = self.parseMaybeAssignment()
+	if err != nil {
+		return nil, err
+	}
+	return self.finishNode(node, start), nil
+}
+
+// finishes parsing a yield expression in a generator, whose argument may be left out
+func (self *Parser) parseYieldExpression(token *Token) (AstNode, error) {
+	if self.inParams {
+		return nil, NewParseError("yield expression may not appear in formal parameters").SetLocation(token.Location)
+	}
+	node := new(YieldExpression)
+	node.Type = YIELD_EXPRESSION
+
+	next, err := self.peekTokenOnSameLine()
+	if err != nil {
+		return nil, err
+	}
+	if next != nil && next.Type == OPERATOR && next.Value == "*" {
+		_, _ = self.nextToken()
+		node.Delegate = true
+	} else if next == nil || next.Value == ":" || (next.Type == DELIMITER && next.Value != "(" && next.Value != "[" && next.Value != "{") {
+		// the argument is missing before a closing bracket, separator or line break
+		return self.finishNode(node, token.Location), nil
+	}
+	node.Argument, err = self.parseMaybeAssignment()
+	if err != nil {
+		return nil, err
+	}
+	return self.finishNode(node, token.Location), nil
+}
+
+// reports the first shorthand initializer recorded since there were count of them
+func (self *Parser) checkShorthandInits(count int) error {
+	if len(self.shorthandInits) > count {
+		location := self.shorthandInits[count]
+		self.shorthandInits = self.shorthandInits[:count]
+		return NewParseError("invalid shorthand property initializer").SetLocation(location)
+	}
+	return nil
+}
+
+// parses a chain of binary operators by precedence climbing,
+// only consuming operators that bind tighter than minPrecedence
+func (self *Parser) parseMaybeBinary(minPrecedence int) (AstNode, error) {
+	err := self.enterNesting()
+	defer self.leaveNesting()
+	if err != nil {
+		return nil, err
+	}
+
+	start := self.startLocation()
+	token, err := self.peekToken()
+	if err != nil {
+		return nil, err
+	}
+	// an unparenthesized unary expression may not be the base of an exponentiation
+	unary := token != nil && (IsUnaryOperator(token) || (token.Type == ATOM && token.Value == "await" && self.inAsync))
+	left, err := self.parseMaybeUnary()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		token, err := self.peekToken()
+		if err != nil {
+			return nil, err
+		}
+		if token == nil || BinaryPrecedence(token) <= minPrecedence {
+			return left, nil
+		}
+		if self.noIn && token.Value == "in" {
+			return left, nil
+		}
+		if unary && token.Type == OPERATOR && token.Value == "**" {
+			return nil, NewParseError("unary operator used immediately before exponentiation expression").SetLocation(token.Location)
+		}
+		unary = false
+		_, _ = self.nextToken()
+
+		left, err = self.parseBinaryExpression(left, token)
+		if err != nil {
+			return nil, err
+		}
+		self.finishNode(left, start)
+
+		// ?? may not be mixed with || or && without parentheses
+		if IsLogicalOperator(token) {
+			next, err := self.peekToken()
+			if err != nil {
+				return nil, err
+			}
+			if next != nil && IsLogicalOperator(next) && (token.Value == "??") != (next.Value == "??") {
+				return nil, NewParseError("cannot mix '%s' and '%s' without parentheses", token.Value, next.Value).SetLocation(next.Location)
+			}
+		}
+	}
+}
+
+// parses a unary or update expression, or a postfix expression
+func (self *Parser) parseMaybeUnary() (AstNode, error) {
+	err := self.enterNesting()
+	defer self.leaveNesting()
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := self.peekToken()
+	if err != nil {
+		return nil, err
+	}
+	if token == nil {
+		return nil, self.unexpectedToken(token, "EXPRESSION")
+	}
+
+	if IsUpdateOperator(token) {
+		_, _ = self.nextToken()
+		return self.parseUpdateExpression(token)
+	}
+	if IsUnaryOperator(token) {
+		_, _ = self.nextToken()
+		return self.parseUnaryExpression(token)
+	}
+	if token.Type == ATOM && token.Value == "await" && self.inAsync {
+		_, _ = self.nextToken()
+		return self.parseAwaitExpression(token)
+	}
+	return self.parseMaybePostfix()
+}
+
+// finishes parsing an await expression in an async function or module
+func (self *Parser) parseAwaitExpression(token *Token) (AstNode, error) {
+	if self.inParams {
+		return nil, NewParseError("await expression may not appear in formal parameters").SetLocation(token.Location)
+	}
+	node := new(AwaitExpression)
+	node.Type = AWAIT_EXPRESSION
+
+	var err error
+	node.Argument, err = self.parseMaybeUnary()
+	if err != nil {
+		return nil, err
+	}
+	return self.finishNode(node, token.Location), nil
+}
+
+// parses a left hand side expression followed by an optional postfix operator
+func (self *Parser) parseMaybePostfix() (AstNode, error) {
+	start := self.startLocation()
+	node, err := self.parseMaybeCall()
+	if err != nil {
+		return nil, err
+	}
+
+	// a postfix operator must be on the same line, so x\n++y is x; ++y
+	token, err := self.peekTokenOnSameLine()
+	if err != nil {
+		return nil, err
+	}
+	if token != nil && IsUpdateOperator(token) {
+		if !_IsSimpleAssignmentTarget(node) {
+			return nil, NewParseError("invalid left-hand side expression in postfix operation").SetLocation(start)
+		}
+		_, _ = self.nextToken()
+		updateNode := new(UpdateExpression)
+		updateNode.Type = UPDATE_EXPRESSION
+		updateNode.Operator = token.Value
+		updateNode.Argument = node
+		updateNode.Prefix = false
+		return self.finishNode(updateNode, start), nil
+	}
+	return node, nil
+}
+
+// parses a primary expression followed by any member accesses and calls
+func (self *Parser) parseMaybeCall() (AstNode, error) {
+	start := self.startLocation()
+	node, err := self.parsePrimaryExpression()
+	if err != nil {
+		return nil, err
+	}
+	return self.parseSubscripts(node, start, true)
+}
+
+// parses member accesses, and calls if allowed, following a node that begins at start
+func (self *Parser) parseSubscripts(node AstNode, start Cursor, allowCalls bool) (AstNode, error) {
+	// set once an optional link is found, so the whole chain can be wrapped
+	chained := false
+	for {
+		token, err := self.peekToken()
+		if err != nil {
+			return nil, err
+		}
+		if token == nil {
+			return self.finishChain(node, start, chained), nil
+		}
+
+		restoreIn := self.allowIn()
+		switch token.Value {
+		case ".", "[":
+			node, err = self.parseMemberExpression(node)
+		case "?.":
+			if !allowCalls {
+				restoreIn()
+				return nil, NewParseError("invalid optional chain from new expression").SetLocation(token.Location)
+			}
+			chained = true
+			node, err = self.parseOptionalExpression(node)
+		case "(":
+			if !allowCalls {
+				restoreIn()
+				return node, nil
+			}
+			_, _ = self.nextToken()
+			node, err = self.parseCallExpression(node)
+		default:
+			if token.Type != TEMPLATE || !strings.HasPrefix(token.Value, "`") {
+				restoreIn()
+				return self.finishChain(node, start, chained), nil
+			}
+			if chained {
+				restoreIn()
+				return nil, NewParseError("invalid tagged template on optional chain").SetLocation(token.Location)
+			}
+			_, _ = self.nextToken()
+			node, err = self.parseTaggedTemplateExpression(node, token)
+		}
+		restoreIn()
+		if err != nil {
+			return nil, err
+		}
+		self.finishNode(node, start)
+	}
+}
+
+// wraps member accesses and calls beginning at start in a chain expression,
+// if they contain an optional link
+func (self *Parser) finishChain(node AstNode, start Cursor, chained bool) AstNode {
+	if !chained {
+		return node
+	}
+	chain := new(ChainExpression)
+	chain.Type = CHAIN_EXPRESSION
+	chain.Expression = node
+	return self.finishNode(chain, start)
+}
+
+// parses the first term of an expression
+func (self *Parser) parsePrimaryExpression() (AstNode, error) {
+	defer self.allowIn()()
+
+	token, err := self.nextToken()
+	if err != nil {
+		return nil, err
+	}
+	if token == nil {
+		return nil, self.unexpectedToken(token, "EXPRESSION")
+	}
+
+	switch token.Value {
+	case "(":
+		return self.parseParenthesizedExpression(token)
+	case "{":
+		return self.parseObjectExpression(token)
+	case "[":
+		return self.parseArrayExpression(token)
+	}
+
+	if token.Type == OPERATOR && (token.Value == "/" || token.Value == "/=") {
+		// the scanner guessed division, but an expression starts here
+		token, err = self.scanner.RescanAsRegex(token)
+		if err != nil {
+			return nil, self.scannerError(err)
+		}
+		self.lastEnd = token.End()
+	}
+
+	switch token.Type {
+	case NUMBER, STRING, REGEX:
+		return self.parseLiteral(token)
+	case TEMPLATE:
+		if strings.HasPrefix(token.Value, "`") {
+			return self.parseTemplateLiteral(token, false)
+		}
+	case ATOM:
+		switch token.Value {
+		case "null", "true", "false":
+			return self.parseLiteral(token)
+		case "this":
+			return self.parseThisExpression(token)
+		case "new":
+			return self.parseNewExpression(token)
+		case "function":
+			return self.parseFunctionExpression(token)
+		case "class":
+			return self.parseClassExpression(token)
+		case "super":
+			return self.parseSuper(token)
+		case "import":
+			return self.parseImportExpression(token)
+		case "async":
+			return self.parseAsyncArrowHead(token)
+		}
+		err = self.checkIdentifierName(token)
+		if err != nil {
+			return nil, err
+		}
+		next, err := self.peekTokenOnSameLine()
+		if err != nil {
+			return nil, err
+		}
+		if next != nil && next.Value == "=>" {
+			return self.parseArrowHead(token)
+		}
+		return self.parseIdentifier(token)
+	}
+
+	return nil, self.unexpectedToken(token, "EXPRESSION")
+}
+
+// finishes parsing a dynamic import() call, or import.meta in a module
+func (self *Parser) parseImportExpression(token *Token) (AstNode, error) {
+	meta, err := self.parseIdentifier(token)
+	if err != nil {
+		return nil, err
+	}
+	next, err := self.nextToken()
+	if err != nil {
+		return nil, err
+	}
+	if next != nil && next.Value == "." {
+		if self.SourceType != "module" {
+			return nil, NewParseError("cannot use 'import.meta' outside a module").SetLocation(token.Location)
+		}
+		node := new(MetaProperty)
+		node.Type = META_PROPERTY
+		node.Keyword = meta
+		property, err := self.expectToken("meta", "META_PROPERTY")
+		if err != nil {
+			return nil, err
+		}
+		node.Property, err = self.parseIdentifier(property)
+		if err != nil {
+			return nil, err
+		}
+		return self.finishNode(node, token.Location), nil
+	}
+	if next == nil || next.Value != "(" {
+		return nil, self.unexpectedToken(next, "IMPORT_EXPRESSION")
+	}
+
+	node := new(ImportExpression)
+	node.Type = IMPORT_EXPRESSION
+	node.Source, err = self.parseMaybeAssignment()
+	if err != nil {
+		return nil, err
+	}
+	next, err = self.nextToken()
+	if err != nil {
+		return nil, err
+	}
+	if next != nil && next.Value == "," {
+		// options may follow, then a trailing comma
+		next, err = self.peekToken()
+		if err != nil {
+			return nil, err
+		}
+		if next != nil && next.Value != ")" {
+			node.Options, err = self.parseMaybeAssignment()
+			if err != nil {
+				return nil, err
+			}
+			next, err = self.peekToken()
+			if err != nil {
+				return nil, err
+			}
+			if next != nil && next.Value == "," {
+				_, _ = self.nextToken()
+			}
+		}
+		next, err = self.nextToken()
+		if err != nil {
+			return nil, err
+		}
+	}
+	if next == nil || next.Value != ")" {
+		return nil, self.unexpectedToken(next, "IMPORT_EXPRESSION")
+	}
+	return self.finishNode(node, token.Location), nil
+}
+
+// parses a spread element, which expands an iterable or object in place
+func (self *Parser) parseSpreadElement() (AstNode, error) {
+	token, err := self.expectToken("...", "SPREAD_ELEMENT")
+	if err != nil {
+		return nil, err
+	}
+
+	node := new(SpreadElement)
+	node.Type = SPREAD_ELEMENT
+	node.Argument, err = self.parseMaybePattern()
+	if err != nil {
+		return nil, err
+	}
+	return self.finishNode(node, token.Location), nil
+}
+
+// finishes parsing a parenthesized expression, or the params of an arrow
+// function when an arrow follows the closing parenthesis
+func (self *Parser) parseParenthesizedExpression(open *Token) (AstNode, error) {
+	items := []AstNode{}
+	// a comma before the closing parenthesis is only allowed in arrow params
+	var trailing *Token
+
+	start := self.startLocation()
+	token, err := self.peekToken()
+	if err != nil {
+		return nil, err
+	}
+	var rest *Token
+	for token == nil || token.Value != ")" {
+		var item AstNode
+		if token != nil && token.Value == "..." {
+			// only allowed as the rest param of an arrow function
+			rest = token
+			item, err = self.parseSpreadElement()
+		} else {
+			item, err = self.parseMaybePattern()
+		}
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+		trailing = nil
+		if rest != nil {
+			break
+		}
+
+		token, err = self.peekToken()
+		if err != nil {
+			return nil, err
+		}
+		if token == nil || token.Value != "," {
+			break
+		}
+		trailing = token
+		_, _ = self.nextToken()
+		token, err = self.peekToken()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// the sequence ends with its last expression, inside the parentheses
+	var sequence AstNode
+	if len(items) > 1 {
+		node := new(SequenceExpression)
+		node.Type = SEQUENCE_EXPRESSION
+		node.Expressions = items
+		sequence = self.finishNode(node, start)
+	}
+	closing, err := self.expectToken(")", "(EXPRESSION...")
+	if err != nil {
+		return nil, err
+	}
+
+	next, err := self.peekTokenOnSameLine()
+	if err != nil {
+		return nil, err
+	}
+	if next != nil && next.Value == "=>" {
+		return self.newArrowHead(items, open.Location, false), nil
+	}
+	if rest != nil {
+		return nil, self.unexpectedToken(rest, "(EXPRESSION...")
+	}
+	if trailing != nil {
+		return nil, self.unexpectedToken(closing, "(EXPRESSION...")
+	}
+	if len(items) == 0 {
+		return nil, self.unexpectedToken(closing, "(EXPRESSION...")
+	}
+	if sequence != nil {
+		return sequence, nil
+	}
+	return items[0], nil
+}
+
+// the params of an arrow function, which are read as an expression
+// until the arrow is reached
+type _ArrowHead struct {
+	AstNodeMeta
+	Params []AstNode
+	Async  bool
+	start  Cursor
+}
+
+func (self *Parser) newArrowHead(params []AstNode, start Cursor, async bool) *_ArrowHead {
+	head := new(_ArrowHead)
+	head.Type = AST_UNKNOWN
+	head.Params = params
+	head.Async = async
+	head.start = start
+	return head
+}
+
+// finishes parsing the single unparenthesized param of an arrow function
+func (self *Parser) parseArrowHead(token *Token) (AstNode, error) {
+	param, err := self.parseIdentifier(token)
+	if err != nil {
+		return nil, err
+	}
+	return self.newArrowHead([]AstNode{param}, token.Location, false), nil
+}
+
+// finishes parsing the params of an async arrow function, or otherwise
+// an identifier named async, or a call to it
+func (self *Parser) parseAsyncArrowHead(token *Token) (AstNode, error) {
+	next, err := self.peekTokenOnSameLine()
+	if err != nil {
+		return nil, err
+	}
+	if next == nil {
+		return self.parseIdentifier(token)
+	}
+
+	switch {
+	case next.Value == "function":
+		return self.parseFunctionExpression(token)
+	case next.Value == "(":
+		callee, err := self.parseIdentifier(token)
+		if err != nil {
+			return nil, err
+		}
+		_, _ = self.nextToken()
+		args, err := self.parseArgumentList()
+		if err != nil {
+			return nil, err
+		}
+		arrow, err := self.peekTokenOnSameLine()
+		if err != nil {
+			return nil, err
+		}
+		if arrow != nil && arrow.Value == "=>" {
+			return self.newArrowHead(args, token.Location, true), nil
+		}
+
+		node := new(CallExpression)
+		node.Type = CALL_EXPRESSION
+		node.Callee = callee
+		node.Arguments = args
+		return self.finishNode(node, token.Location), nil
+	case next.Type == ATOM && next.Value != "in" && next.Value != "instanceof":
+		_, _ = self.nextToken()
+		param, err := self.parseIdentifier(next)
+		if err != nil {
+			return nil, err
+		}
+		arrow, err := self.peekTokenOnSameLine()
+		if err != nil {
+			return nil, err
+		}
+		if arrow == nil || arrow.Value != "=>" {
+			return nil, self.unexpectedToken(next, "ARROW_FUNCTION_EXPRESSION")
+		}
+		return self.newArrowHead([]AstNode{param}, token.Location, true), nil
+	}
+	return self.parseIdentifier(token)
+}
+
+// finishes parsing an arrow function beginning at start, given its params
+func (self *Parser) parseArrowFunction(head *_ArrowHead, start Cursor) (AstNode, error) {
+	node := new(ArrowFunctionExpression)
+	node.Type = ARROW_FUNCTION_EXPRESSION
+	node.Async = head.Async
+
+	var err error
+	node.Params, err = self.toArrowParams(head)
+	if err != nil {
+		return nil, err
+	}
+	_, err = self.expectToken("=>", "ARROW_FUNCTION_EXPRESSION")
+	if err != nil {
+		return nil, err
+	}
+
+	captureStart := self.scanner.Location
+	self.scanner.BeginCapture()
+	restoreAwaitYield := self.allowAwaitYield(node.Async, false)
+	node.Body, node.Expression, err = self.parseArrowBody(node.Params)
+	restoreAwaitYield()
+	capture := self.scanner.FinishCapture()
+	if err != nil {
+		return nil, err
+	}
+	// an expression body is only known to end once the next token is read
+	node.Source = _TrimFunctionSource(capture.Prefix(self.lastEnd.Offset - captureStart.Offset))
+	if node.Expression {
+		node.Source = strings.TrimSpace(node.Source)
+	}
+	node.Params, node.Defaults, node.Rest = self.splitParams(node.Params)
+
+	return self.finishNode(node, start), nil
+}
+
+// parses the block or expression body of an arrow function,
+// reporting whether it is an expression
+func (self *Parser) parseArrowBody(params []AstNode) (AstNode, bool, error) {
+	token, err := self.peekToken()
+	if err != nil {
+		return nil, false, err
+	}
+	if token != nil && token.Value == "{" {
+		body, err := self.parseFunctionBody(params)
+		return body, false, err
+	}
+
+	defer self.enterFunction(params)()
+	body, err := self.parseMaybeAssignment()
+	return body, true, err
+}
+
+// reinterprets the expressions read before an arrow as its params
+func (self *Parser) toArrowParams(head *_ArrowHead) ([]AstNode, error) {
+	params := []AstNode{}
+	names := []string{}
+	for i, param := range head.Params {
+		var err error
+		switch param.AstType() {
+		case IDENTIFIER, ARRAY_EXPRESSION, OBJECT_EXPRESSION, ARRAY_PATTERN, OBJECT_PATTERN, ASSIGNMENT_EXPRESSION:
+			param, err = self.toPatternElement(param, true, head.start)
+		case SPREAD_ELEMENT:
+			if i != len(head.Params)-1 {
+				return nil, NewParseError("rest parameter must be last formal parameter").SetLocation(head.start)
+			}
+			param, err = self.toRestElement(param.(*SpreadElement), true, head.start)
+		default:
+			return nil, NewParseError("malformed arrow function parameter list").SetLocation(head.start)
+		}
+		if err != nil {
+			return nil, err
+		}
+		params = append(params, param)
+		names = _CollectBoundNames(param, names)
+	}
 
-			switch token.Type {
-			case STRING, NUMBER:
-				self.scanner.UnNext()
-				return node, nil
-			case ATOM:
-				if token.Value == "instanceof" {
-					node, err = self.parseBinaryExpression(node, token)
-					if err != nil {
-						return nil, err
-					}
-					continue
-				}
-				self.scanner.UnNext()
-				return node, nil
-			case OPERATOR:
-				switch token.Value {
-				case "=", "+=", "-=":
-					self.scanner.UnNext()
-					node, err = self.parseAssignmentExpression(node)
-				case ".":
-					self.scanner.UnNext()
-					node, err = self.parseMemberExpression(node)
-				default:
-					node, err = self.parseBinaryExpression(node, token)
-				}
-				if err != nil {
-					return nil, err
-				}
-				continue
+	seen := map[string]bool{}
+	for _, name := range names {
+		if seen[name] {
+			return nil, NewParseError("duplicate parameter name not allowed in this context").SetLocation(head.start)
+		}
+		// read as an identifier before the arrow showed the function is async
+		if name == "await" && head.Async {
+			return nil, NewParseError("'await' may not be used as an identifier here").SetLocation(head.start)
+		}
+		seen[name] = true
+	}
+	return params, nil
+}
+
+// finishes parsing a function expression
+func (self *Parser) parseFunctionExpression(token *Token) (AstNode, error) {
+	var err error
+	start := token.Location
+
+	node := new(FunctionExpression)
+	node.Type = FUNCTION_EXPRESSION
+
+	if token.Value == "async" {
+		node.Async = true
+		token, err = self.nextToken()
+		if err != nil {
+			return nil, err
+		}
+	}
+	if token == nil || token.Value != "function" {
+		return nil, self.unexpectedToken(token, "FUNCTION_EXPRESSION")
+	}
+
+	token, err = self.nextToken()
+	if err != nil {
+		return nil, err
+	}
+	if token != nil && token.Type == OPERATOR && token.Value == "*" {
+		node.Generator = true
+		token, err = self.nextToken()
+		if err != nil {
+			return nil, err
+		}
+	}
+	if token == nil {
+		return nil, self.unexpectedToken(token, "FUNCTION_EXPRESSION")
+	}
+
+	if token.Type == ATOM {
+		node.Id, err = self.parseIdentifier(token)
+		if err != nil {
+			return nil, err
+		}
+		token, err = self.nextToken()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if token == nil || token.Value != "(" {
+		return nil, self.unexpectedToken(token, "FUNCTION_EXPRESSION")
+	}
+	defer self.allowSuper(false, false)()
+	defer self.allowAwaitYield(node.Async, node.Generator)()
+	node.Params, err = self.parseParamList()
+	if err != nil {
+		return nil, err
+	}
+
+	captureStart := self.scanner.Location
+	self.scanner.BeginCapture()
+	node.Body, err = self.parseFunctionBody(node.Params)
+	capture := self.scanner.FinishCapture()
+	if err != nil {
+		return nil, err
+	}
+	// the token after the body may have been read, to find comments following it
+	node.Source = _TrimFunctionSource(capture.Prefix(self.lastEnd.Offset - captureStart.Offset))
+	node.Params, node.Defaults, node.Rest = self.splitParams(node.Params)
+
+	return self.finishNode(node, start), nil
+}
+
+// parses a class declaration into a statement node,
+// whose name may be left out when anonymous, as after export default
+func (self *Parser) parseClassDeclaration(anonymous bool) (AstNode, error) {
+	node := new(ClassDeclaration)
+	node.Type = CLASS_DECLARATION
+
+	_, err := self.expectToken("class", "CLASS_DECLARATION")
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := self.nextToken()
+	if err != nil {
+		return nil, err
+	}
+	if anonymous && token != nil && (token.Value == "{" || token.Value == "extends") {
+		self.unreadToken()
+	} else {
+		if token == nil || token.Type != ATOM || token.Value == "extends" {
+			return nil, self.unexpectedToken(token, "CLASS_DECLARATION")
+		}
+		node.Id, err = self.parseIdentifier(token)
+		if err != nil {
+			return nil, err
+		}
+		err = self.declareLexical(token.Value, token.Location)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	node.SuperClass, node.Body, err = self.parseClassTail()
+	if err != nil {
+		return nil, err
+	}
+	return node, nil
+}
+
+// finishes parsing a class expression, which may be anonymous
+func (self *Parser) parseClassExpression(token *Token) (AstNode, error) {
+	node := new(ClassExpression)
+	node.Type = CLASS_EXPRESSION
+
+	next, err := self.peekToken()
+	if err != nil {
+		return nil, err
+	}
+	if next != nil && next.Type == ATOM && next.Value != "extends" {
+		_, _ = self.nextToken()
+		node.Id, err = self.parseIdentifier(next)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	node.SuperClass, node.Body, err = self.parseClassTail()
+	if err != nil {
+		return nil, err
+	}
+	return self.finishNode(node, token.Location), nil
+}
+
+// parses the optional heritage of a class, and its body
+func (self *Parser) parseClassTail() (AstNode, AstNode, error) {
+	err := self.enterNesting()
+	defer self.leaveNesting()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	token, err := self.peekToken()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var superClass AstNode
+	if token != nil && token.Value == "extends" {
+		_, _ = self.nextToken()
+		superClass, err = self.parseMaybeCall()
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	body, err := self.parseClassBody(superClass != nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	return superClass, body, nil
+}
+
+// parses the braces and members of a class body, where only the
+// constructor of a derived class may call super
+func (self *Parser) parseClassBody(derived bool) (AstNode, error) {
+	start, err := self.expectToken("{", "CLASS_BODY")
+	if err != nil {
+		return nil, err
+	}
+
+	node := new(ClassBody)
+	node.Type = CLASS_BODY
+	node.Body = []AstNode{}
+
+	self.enterClass()
+	hasConstructor := false
+	for {
+		token, err := self.peekToken()
+		if err != nil {
+			return nil, err
+		}
+		if token == nil {
+			return nil, self.unexpectedToken(token, "CLASS_BODY")
+		}
+		if token.Value == "}" {
+			_, _ = self.nextToken()
+			break
+		}
+		if token.Value == ";" {
+			_, _ = self.nextToken()
+			continue
+		}
+
+		member, err := self.parseClassMember(derived)
+		if err != nil {
+			return nil, err
+		}
+		if method, ok := member.(*MethodDefinition); ok && method.Kind == "constructor" {
+			if hasConstructor {
+				return nil, NewParseError("a class may only have one constructor").SetLocation(token.Location)
 			}
+			hasConstructor = true
 		}
+		node.Body = append(node.Body, member)
+	}
+	err = self.leaveClass()
+	if err != nil {
+		return nil, err
+	}
 
-		var perr *ParseError
-		if node != nil {
-			perr = NewParseError("cannot parse (EXPRESSION...<<\"%s\"(%s)", token.Value, token.Type)
-		} else {
-			perr = NewParseError("cannot parse EXPRESSION<<'%s'(%s)", token.Value, token.Type)
+	return self.finishNode(node, start.Location), nil
+}
+
+// parses a method, field or static block of a class body
+func (self *Parser) parseClassMember(derived bool) (AstNode, error) {
+	start := self.startLocation()
+	token, err := self.nextToken()
+	if err != nil {
+		return nil, err
+	}
+
+	static := false
+	if token.Value == "static" && !self.isMethodNameEnd() {
+		next, err := self.peekToken()
+		if err != nil {
+			return nil, err
+		}
+		if next.Value == "{" {
+			return self.parseStaticBlock(start)
+		}
+		static = true
+		token, _ = self.nextToken()
+	}
+
+	kind, async, generator, token, err := self.parseMethodModifiers(token)
+	if err != nil {
+		return nil, err
+	}
+	key, computed, err := self.parsePropertyKey(token, "CLASS_BODY")
+	if err != nil {
+		return nil, err
+	}
+	name := ""
+	if !computed {
+		name = _PropertyKeyName(key)
+	}
+	if static && name == "prototype" {
+		return nil, NewParseError("classes may not have a static property named 'prototype'").SetLocation(token.Location)
+	}
+
+	next, err := self.peekToken()
+	if err != nil {
+		return nil, err
+	}
+	if next == nil || (next.Value != "(" && (kind != "method" || async || generator)) {
+		return nil, self.unexpectedToken(next, "CLASS_BODY")
+	}
+	if next.Value != "(" {
+		if name == "constructor" {
+			return nil, NewParseError("classes may not have a field named 'constructor'").SetLocation(token.Location)
+		}
+		if token.Type == PRIVATE_NAME {
+			err = self.declarePrivateName(token, "field", static)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return self.parseClassField(key, computed, static, start)
+	}
+
+	node := new(MethodDefinition)
+	node.Type = METHOD_DEFINITION
+	node.Key = key
+	node.Computed = computed
+	node.Kind = kind
+	node.Static = static
+	if name == "constructor" && !static && token.Type != PRIVATE_NAME {
+		if kind != "method" {
+			return nil, NewParseError("class constructor may not be an accessor").SetLocation(token.Location)
+		}
+		if async {
+			return nil, NewParseError("class constructor may not be an async method").SetLocation(token.Location)
+		}
+		if generator {
+			return nil, NewParseError("class constructor may not be a generator").SetLocation(token.Location)
+		}
+		node.Kind = "constructor"
+	}
+	if token.Type == PRIVATE_NAME {
+		err = self.declarePrivateName(token, kind, static)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	node.Value, err = self.parseMethodFunction(node.Kind == "constructor" && derived, async, generator)
+	if err != nil {
+		return nil, err
+	}
+	err = self.checkAccessorParams(kind, node.Value, token)
+	if err != nil {
+		return nil, err
+	}
+	return self.finishNode(node, start), nil
+}
+
+// whether the modifier just read is instead the name of a property or method
+func (self *Parser) isMethodNameEnd() bool {
+	next, err := self.peekToken()
+	if err != nil || next == nil {
+		return true
+	}
+	switch next.Value {
+	case "(", "=", ";", "}", ",", ":":
+		return true
+	}
+	return false
+}
+
+// reads the async, generator and accessor modifiers of a method given its first token,
+// returning its kind and the token of its name
+func (self *Parser) parseMethodModifiers(token *Token) (string, bool, bool, *Token, error) {
+	var err error
+	kind := "method"
+	async := false
+	generator := false
+
+	if token.Value == "async" && !self.isMethodNameEnd() {
+		next, err := self.peekTokenOnSameLine()
+		if err != nil {
+			return "", false, false, nil, err
+		}
+		if next != nil {
+			async = true
+			token, _ = self.nextToken()
+		}
+	}
+	if token.Type == OPERATOR && token.Value == "*" {
+		generator = true
+		token, err = self.nextToken()
+	} else if !async && (token.Value == "get" || token.Value == "set") && !self.isMethodNameEnd() {
+		kind = token.Value
+		token, err = self.nextToken()
+	}
+	if err != nil {
+		return "", false, false, nil, err
+	}
+	return kind, async, generator, token, nil
+}
+
+// checks the number of params of a getter or setter
+func (self *Parser) checkAccessorParams(kind string, value AstNode, token *Token) error {
+	function := value.(*FunctionExpression)
+	params := len(function.Params)
+	if function.Rest != nil {
+		params += 1
+	}
+	if kind == "get" && params != 0 {
+		return NewParseError("getter must not have any formal parameters").SetLocation(token.Location)
+	}
+	if kind == "set" && params != 1 {
+		return NewParseError("setter must have exactly one formal parameter").SetLocation(token.Location)
+	}
+	if kind == "set" && (function.Rest != nil || function.Params[0].AstType() == REST_ELEMENT) {
+		return NewParseError("setter function argument must not be a rest parameter").SetLocation(token.Location)
+	}
+	return nil
+}
+
+// parses the name of a class member or object property,
+// which may be computed from an expression
+func (self *Parser) parsePropertyKey(token *Token, context string) (AstNode, bool, error) {
+	if token == nil {
+		return nil, false, self.unexpectedToken(token, context)
+	}
+	if token.Value == "[" {
+		key, err := self.parseMaybeAssignment()
+		if err != nil {
+			return nil, false, err
+		}
+		_, err = self.expectToken("]", context)
+		if err != nil {
+			return nil, false, err
 		}
-		// return nil, perr.SetLocation(token.Location)
-		panic(perr.SetLocation(token.Location))
+		return key, true, nil
+	}
+
+	var key AstNode
+	var err error
+	switch token.Type {
+	case PRIVATE_NAME:
+		key, err = self.parsePrivateIdentifier(token)
+	case ATOM:
+		key, err = self.parseIdentifier(token)
+	case STRING, NUMBER:
+		key, err = self.parseLiteral(token)
+	default:
+		return nil, false, self.unexpectedToken(token, context)
+	}
+	return key, false, err
+}
+
+// finishes parsing a class field after its name, with an optional initializer
+func (self *Parser) parseClassField(key AstNode, computed bool, static bool, start Cursor) (AstNode, error) {
+	node := new(PropertyDefinition)
+	node.Type = PROPERTY_DEFINITION
+	node.Key = key
+	node.Computed = computed
+	node.Static = static
+
+	token, err := self.peekToken()
+	if err != nil {
+		return nil, err
+	}
+	if token != nil && token.Value == "=" {
+		_, _ = self.nextToken()
+		// initializers run like methods of the class
+		leaveFunction := self.enterFunction(nil)
+		restoreSuper := self.allowSuper(false, true)
+		restoreAwaitYield := self.allowAwaitYield(false, false)
+		self.noArguments = true
+		node.Value, err = self.parseMaybeAssignment()
+		restoreAwaitYield()
+		restoreSuper()
+		leaveFunction()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// fields end with a semicolon, a newline or the end of the class body
+	token, err = self.peekTokenOnSameLine()
+	if err != nil {
+		return nil, err
+	}
+	if token != nil && token.Value == ";" {
+		_, _ = self.nextToken()
+	} else if token != nil && token.Value != "}" {
+		return nil, self.unexpectedToken(token, "PROPERTY_DEFINITION")
 	}
 
-	panic("unreachable")
+	return self.finishNode(node, start), nil
 }
 
-// finishes parsing a function expression
-func (self *Parser) parseFunctionExpression(token *Token) (AstNode, error) {
-	if token.Value != "function" {
-		err := NewParseError("cannot parse FUNCTION_EXPRESSION<<'%s'(%s)", token.Value, token.Type)
-		return nil, err.SetLocation(token.Location)
+// finishes parsing a static initialization block beginning at start
+func (self *Parser) parseStaticBlock(start Cursor) (AstNode, error) {
+	node := new(StaticBlock)
+	node.Type = STATIC_BLOCK
+
+	leaveFunction := self.enterFunction(nil)
+	restoreSuper := self.allowSuper(false, true)
+	restoreAwaitYield := self.allowAwaitYield(false, false)
+	self.noArguments, self.inStaticBlock = true, true
+	block, err := self.parseBlock()
+	restoreAwaitYield()
+	restoreSuper()
+	leaveFunction()
+	if err != nil {
+		return nil, err
 	}
+	node.Body = block.(*BlockStatement).Body
 
-	var err error
+	return self.finishNode(node, start), nil
+}
 
+// parses the params and body of a class or object method into a function expression
+func (self *Parser) parseMethodFunction(superCall bool, async bool, generator bool) (AstNode, error) {
 	node := new(FunctionExpression)
 	node.Type = FUNCTION_EXPRESSION
+	node.Async = async
+	node.Generator = generator
 
-	token, err = self.scanner.Next()
+	token, err := self.expectToken("(", "FUNCTION_EXPRESSION")
 	if err != nil {
 		return nil, err
 	}
-	if token == nil {
-		err := NewParseError("cannot parse FUNCTION_EXPRESSION<<EOF")
-		return nil, err.SetLocation(self.scanner.Location)
+	defer self.allowSuper(superCall, true)()
+	defer self.allowAwaitYield(async, generator)()
+	node.Params, err = self.parseParamList()
+	if err != nil {
+		return nil, err
 	}
 
-	if token.Type == ATOM {
-		node.Id, err = self.parseIdentifier(token)
-		if err != nil {
-			return nil, err
-		}
-		token, err = self.scanner.Next()
-		if err != nil {
-			return nil, err
-		}
+	captureStart := self.scanner.Location
+	self.scanner.BeginCapture()
+	node.Body, err = self.parseFunctionBody(node.Params)
+	capture := self.scanner.FinishCapture()
+	if err != nil {
+		return nil, err
 	}
+	// the token after the body may have been read, to find comments following it
+	node.Source = _TrimFunctionSource(capture.Prefix(self.lastEnd.Offset - captureStart.Offset))
+	node.Params, node.Defaults, node.Rest = self.splitParams(node.Params)
 
-	if token.Value != "(" {
-		err := NewParseError("cannot parse FUNCTION_EXPRESSION<<function %s:%s", token.Type, token.Value)
-		return nil, err.SetLocation(token.Location)
-	}
-	node.Params, err = self.parseParamList()
-	node.Defaults = []AstNode{}
+	return self.finishNode(node, token.Location), nil
+}
 
-	self.scanner.BeginCapture()
-	node.Body, err = self.parseBlockStatement()
+// finishes parsing super, which must be called or have a property read
+func (self *Parser) parseSuper(token *Token) (AstNode, error) {
+	next, err := self.peekToken()
 	if err != nil {
 		return nil, err
 	}
-	capture := self.scanner.FinishCapture()
-	node.Source = _TrimFunctionSource(capture.String())
+	allowed := false
+	if next != nil {
+		switch next.Value {
+		case "(":
+			allowed = self.superCall
+		case ".", "[":
+			allowed = self.superProperty
+		}
+	}
+	if !allowed {
+		return nil, NewParseError("'super' keyword unexpected here").SetLocation(token.Location)
+	}
 
-	return node, nil
+	node := new(Super)
+	node.Type = SUPER
+	return self.finishNode(node, token.Location), nil
+}
+
+// finishes parsing a private name, as a class member or after a dot
+func (self *Parser) parsePrivateIdentifier(token *Token) (AstNode, error) {
+	node := new(PrivateIdentifier)
+	node.Type = PRIVATE_IDENTIFIER
+	node.Name = strings.TrimPrefix(token.Value, "#")
+	return self.finishNode(node, token.Location), nil
 }
 
 // finishes parsing an object expression
 func (self *Parser) parseObjectExpression(token *Token) (AstNode, error) {
 	if token.Value != "{" {
-		err := NewParseError("cannot parse OBJECT_EXPRESSION<<'%s'(%s)", token.Value, token.Type)
-		return nil, err.SetLocation(token.Location)
+		return nil, self.unexpectedToken(token, "OBJECT_EXPRESSION")
 	}
 
 	var err error
+	start := token.Location
 
 	node := new(ObjectExpression)
 	node.Type = OBJECT_EXPRESSION
+	node.Properties = []AstNode{}
 
 	for {
-		token, err = self.scanner.Next()
+		token, err = self.nextToken()
 		if err != nil {
 			return nil, err
 		}
 		if token == nil {
-			err := NewParseError("cannot parse OBJECT_EXPRESSION<<{EOF")
-			return nil, err.SetLocation(self.scanner.Location)
+			return nil, self.unexpectedToken(token, "OBJECT_EXPRESSION")
 		}
 
-		switch token.Value {
-		case ",", "\n":
+		if token.Value == "," {
 			continue
 		}
 		if token.Value == "}" {
 			break
 		}
-
-		propNode := new(Property)
-		propNode.Type = PROPERTY
-		propNode.Kind = "init"
-
-		switch token.Type {
-		case STRING, NUMBER:
-			propNode.Key, err = self.parseLiteral(token)
-		case ATOM:
-			propNode.Key, err = self.parseIdentifier(token)
-		default:
-			err = NewParseError("cannot parse OBJECT_EXPRESSION<<{'%s'(%s)", token.Value, token.Type).SetLocation(token.Location)
+		if token.Value == "..." {
+			self.unreadToken()
+			spread, err := self.parseSpreadElement()
+			if err != nil {
+				return nil, err
+			}
+			node.Properties = append(node.Properties, spread)
+			next, err := self.peekToken()
+			if err != nil {
+				return nil, err
+			}
+			if next != nil && next.Value == "," {
+				self.markSpreadComma(spread)
+			}
+			continue
 		}
+
+		property, err := self.parseObjectProperty(token)
 		if err != nil {
 			return nil, err
 		}
+		node.Properties = append(node.Properties, property)
+	}
 
-		token, err = self.scanner.Next()
+	return self.finishNode(node, start), nil
+}
+
+// parses a property of an object literal given its first token,
+// which may be a method, an accessor or a shorthand for a variable
+func (self *Parser) parseObjectProperty(token *Token) (AstNode, error) {
+	start := token.Location
+	node := new(Property)
+	node.Type = PROPERTY
+	node.Kind = "init"
+
+	kind, async, generator, token, err := self.parseMethodModifiers(token)
+	if err != nil {
+		return nil, err
+	}
+	node.Key, node.Computed, err = self.parsePropertyKey(token, "OBJECT_EXPRESSION")
+	if err != nil {
+		return nil, err
+	}
+	if token.Type == PRIVATE_NAME {
+		return nil, self.unexpectedToken(token, "OBJECT_EXPRESSION")
+	}
+
+	next, err := self.peekToken()
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case kind != "method" || async || generator || (next != nil && next.Value == "("):
+		node.Value, err = self.parseMethodFunction(false, async, generator)
 		if err != nil {
 			return nil, err
 		}
-		if token == nil || token.Value != ":" {
-			err := NewParseError("cannot parse OBJECT_EXPRESSION<<{_key_'%s'(%s)", token.Value, token.Type)
-			// return nil, err.SetLocation(self.scanner.Location)
-			panic(err.SetLocation(self.scanner.Location))
+		if kind == "method" {
+			node.Method = true
+		} else {
+			node.Kind = kind
 		}
-
-		propNode.Value, err = self.parseExpression()
+		err = self.checkAccessorParams(kind, node.Value, token)
 		if err != nil {
 			return nil, err
 		}
-
-		node.Properties = append(node.Properties, propNode)
+	case next != nil && next.Value == ":":
+		_, _ = self.nextToken()
+		node.Value, err = self.parseMaybePattern()
+		if err != nil {
+			return nil, err
+		}
+	case token.Type == ATOM && !node.Computed:
+		err = self.checkIdentifierName(token)
+		if err != nil {
+			return nil, err
+		}
+		node.Shorthand = true
+		node.Value = node.Key
+		if next != nil && next.Value == "=" {
+			// only valid once the object is reinterpreted as a pattern
+			_, _ = self.nextToken()
+			self.shorthandInits = append(self.shorthandInits, next.Location)
+			value := new(AssignmentPattern)
+			value.Type = ASSIGNMENT_PATTERN
+			value.Left = node.Key
+			value.Right, err = self.parseMaybeAssignment()
+			if err != nil {
+				return nil, err
+			}
+			node.Value = self.finishNode(value, token.Location)
+		}
+	default:
+		return nil, self.unexpectedToken(next, "OBJECT_EXPRESSION")
 	}
 
-	return node, nil
+	return self.finishNode(node, start), nil
 }
 
 // finishes parsing an array expression
 func (self *Parser) parseArrayExpression(token *Token) (AstNode, error) {
 	if token.Value != "[" {
-		err := NewParseError("cannot parse ARRAY_EXPRESSION<<'%s'(%s)", token.Value, token.Type)
-		return nil, err.SetLocation(token.Location)
+		return nil, self.unexpectedToken(token, "ARRAY_EXPRESSION")
 	}
 
+	start := token.Location
 	node := new(ArrayExpression)
 	node.Type = ARRAY_EXPRESSION
+	node.Elements = []AstNode{}
 
 	for {
-		nextNode, err := self.parseExpression()
+		token, err := self.peekToken()
 		if err != nil {
 			return nil, err
 		}
-		if nextNode != nil {
-			node.Elements = append(node.Elements, nextNode)
-		}
-
-		var token *Token
-		for {
-			token, err = self.scanner.Next()
-			if err != nil {
-				return nil, err
-			}
-			if token == nil {
-				err := NewParseError("cannot parse ARRAY_EXPRESSION<<[EOF")
-				return nil, err.SetLocation(self.scanner.Location)
-			}
-			if token.Value != "\n" {
-				break
-			}
+		if token == nil {
+			return nil, self.unexpectedToken(token, "ARRAY_EXPRESSION")
 		}
 
-		if token.Value == "]" {
-			break
-		}
 		switch token.Value {
+		case "]":
+			_, _ = self.nextToken()
+			return self.finishNode(node, start), nil
 		case ",":
+			// elision
+			_, _ = self.nextToken()
+			node.Elements = append(node.Elements, nil)
 			continue
 		}
 
-		perr := NewParseError("cannot parse ARRAY_EXPRESSION<<[...%s", token.Value)
-		return nil, perr.SetLocation(self.scanner.Location)
-	}
+		var nextNode AstNode
+		if token.Value == "..." {
+			nextNode, err = self.parseSpreadElement()
+		} else {
+			nextNode, err = self.parseMaybePattern()
+		}
+		if err != nil {
+			return nil, err
+		}
+		node.Elements = append(node.Elements, nextNode)
 
-	return node, nil
+		token, err = self.nextToken()
+		if err != nil {
+			return nil, err
+		}
+		if token == nil || (token.Value != "]" && token.Value != ",") {
+			return nil, self.unexpectedToken(token, "ARRAY_EXPRESSION")
+		}
+		if token.Value == "," && nextNode.AstType() == SPREAD_ELEMENT {
+			self.markSpreadComma(nextNode)
+		}
+		if token.Value == "]" {
+			return self.finishNode(node, start), nil
+		}
+	}
 }
 
 // finishes parsing a call expression
@@ -914,30 +4109,124 @@ func (self *Parser) parseCallExpression(left AstNode) (AstNode, error) {
 	return node, nil
 }
 
+// parses an optional member access or call beginning with ?.
+func (self *Parser) parseOptionalExpression(left AstNode) (AstNode, error) {
+	_, err := self.nextToken()
+	if err != nil {
+		return nil, err
+	}
+	next, err := self.peekToken()
+	if err != nil {
+		return nil, err
+	}
+	if next == nil || next.Value != "(" {
+		self.unreadToken()
+		return self.parseMemberExpression(left)
+	}
+
+	_, _ = self.nextToken()
+	node, err := self.parseCallExpression(left)
+	if err != nil {
+		return nil, err
+	}
+	node.(*CallExpression).Optional = true
+	return node, nil
+}
+
+// finishes parsing a tagged template given the tag and the first template token
+func (self *Parser) parseTaggedTemplateExpression(left AstNode, token *Token) (AstNode, error) {
+	node := new(TaggedTemplateExpression)
+	node.Type = TAGGED_TEMPLATE_EXPRESSION
+	node.Tag = left
+
+	quasi, err := self.parseTemplateLiteral(token, true)
+	if err != nil {
+		return nil, err
+	}
+	node.Quasi = quasi
+
+	return node, nil
+}
+
+// finishes parsing a template literal given its first template token
+// tagged templates may contain invalid escapes, which have no cooked value
+func (self *Parser) parseTemplateLiteral(token *Token, tagged bool) (AstNode, error) {
+	start := token.Location
+	node := new(TemplateLiteral)
+	node.Type = TEMPLATE_LITERAL
+	node.Quasis = []AstNode{}
+	node.Expressions = []AstNode{}
+
+	for {
+		element, err := self.parseTemplateElement(token, tagged)
+		if err != nil {
+			return nil, err
+		}
+		node.Quasis = append(node.Quasis, element)
+		if element.Tail {
+			return self.finishNode(node, start), nil
+		}
+
+		expression, err := self.parseExpression()
+		if err != nil {
+			return nil, err
+		}
+		node.Expressions = append(node.Expressions, expression)
+
+		// the closing brace of the substitution begins the next template token
+		token, err = self.nextToken()
+		if err != nil {
+			return nil, err
+		}
+		if token == nil || token.Type != TEMPLATE || !strings.HasPrefix(token.Value, "}") {
+			return nil, self.unexpectedToken(token, "TEMPLATE_LITERAL")
+		}
+	}
+}
+
+// parses the text of one template token
+func (self *Parser) parseTemplateElement(token *Token, tagged bool) (*TemplateElement, error) {
+	node := new(TemplateElement)
+	node.Type = TEMPLATE_ELEMENT
+	node.Value.Raw, node.Tail = TemplateElementRaw(token.Value)
+
+	cooked, err := DecodeTemplateElement(token.Value, token.Location)
+	if err != nil {
+		if !tagged {
+			return nil, self.scannerError(err)
+		}
+		node.Value.Cooked = nil
+	} else {
+		node.Value.Cooked = cooked
+	}
+
+	self.finishNode(node, token.Location)
+	return node, nil
+}
+
 // finishes parsing a assignment expression given a left node
 func (self *Parser) parseAssignmentExpression(left AstNode) (AstNode, error) {
 	node := new(AssignmentExpression)
 	node.Type = ASSIGNMENT_EXPRESSION
 	node.Left = left
 
-	token, err := self.scanner.Next()
-	if token == nil || err != nil {
+	token, err := self.nextToken()
+	if err != nil {
 		return nil, err
 	}
 
-	switch token.Value {
-	case "=", "+=", "-=":
+	if token != nil && IsAssignmentOperator(token) {
 		node.Operator = token.Value
-		right, err := self.parseExpression()
-		if token == nil || err != nil {
+		// right associative
+		right, err := self.parseMaybeAssignment()
+		if err != nil {
 			return nil, err
 		}
 		node.Right = right
 		return node, nil
 	}
 
-	perr := NewParseError("cannot parse ASSIGNMENT_EXPRESSION<<'%s'(%s)", token.Value, token.Type)
-	return nil, perr.SetLocation(token.Location)
+	return nil, self.unexpectedToken(token, "ASSIGNMENT_EXPRESSION")
 }
 
 // finishes parsing a member expression given a left node
@@ -946,20 +4235,48 @@ func (self *Parser) parseMemberExpression(left AstNode) (AstNode, error) {
 	node.Type = MEMBER_EXPRESSION
 	node.Object = left
 
-	token, err := self.scanner.Next()
-	if token == nil || err != nil {
+	token, err := self.nextToken()
+	if err != nil {
 		return nil, err
 	}
+	if token == nil {
+		return nil, self.unexpectedToken(token, "MEMBER_EXPRESSION")
+	}
 
-	switch token.Value {
+	value := token.Value
+	if value == "?." {
+		// an optional link is followed by a name or a computed member
+		node.Optional = true
+		value = "."
+		next, err := self.peekToken()
+		if err != nil {
+			return nil, err
+		}
+		if next != nil && next.Value == "[" {
+			_, _ = self.nextToken()
+			value = "["
+		}
+	}
+
+	switch value {
 	case ".":
 		node.Computed = false
-		token, err = self.scanner.Next()
-		if token == nil || err != nil {
+		token, err = self.nextToken()
+		if err != nil {
 			return nil, err
 		}
+		if token != nil && token.Type == PRIVATE_NAME {
+			node.Property, err = self.parsePrivateIdentifier(token)
+			if err != nil {
+				return nil, err
+			}
+			return node, self.usePrivateName(token)
+		}
+		if token == nil || token.Type != ATOM {
+			return nil, self.unexpectedToken(token, "MEMBER_EXPRESSION")
+		}
 		right, err := self.parseIdentifier(token)
-		if right == nil || err != nil {
+		if err != nil {
 			return nil, err
 		}
 		node.Property = right
@@ -967,41 +4284,49 @@ func (self *Parser) parseMemberExpression(left AstNode) (AstNode, error) {
 	case "[":
 		node.Computed = true
 		right, err := self.parseExpression()
-		if right == nil || err != nil {
+		if err != nil {
 			return nil, err
 		}
 		node.Property = right
-		token, err = self.scanner.Next()
+		_, err = self.expectToken("]", "MEMBER_EXPRESSION")
 		if err != nil {
 			return nil, err
 		}
-		if token.Value != "]" {
-			return nil, NewParseError("cannot parse MEMBER_EXPRESSION<<[...%s", token.Value).SetLocation(token.Location)
-		}
 		return node, nil
 	}
 
-	perr := NewParseError("cannot parse MEMBER_EXPRESSION<<%s", token.Value)
-	return nil, perr.SetLocation(token.Location)
+	return nil, self.unexpectedToken(token, "MEMBER_EXPRESSION")
 }
 
-// finishes parsing a binary expression given a left node
+// finishes parsing a binary expression given a left node and operator token
 func (self *Parser) parseBinaryExpression(left AstNode, token *Token) (AstNode, error) {
-	node := new(BinaryExpression)
-	node.Type = BINARY_EXPRESSION
-	node.Left = left
-	node.Operator = token.Value
-
-	var right AstNode
-	var err error
-	if token.Value == "+" || token.Value == "-" {
-		right, err = self.parseExpressionUntil([]string{"+","-"})
-	} else {
-		right, err = self.parseExpressionUntil([]string{"+","-","*","/"})
+	precedence := BinaryPrecedence(token)
+	if IsRightAssociative(token) {
+		precedence -= 1
 	}
-	if token == nil || err != nil {
+	if token.Value == "??" {
+		// stop before && as well as ||, so that mixing them can be reported
+		precedence = BinaryPrecedence(&Token{Type: OPERATOR, Value: "&&"})
+	}
+
+	right, err := self.parseMaybeBinary(precedence)
+	if err != nil {
 		return nil, err
 	}
+
+	if IsLogicalOperator(token) {
+		node := new(LogicalExpression)
+		node.Type = LOGICAL_EXPRESSION
+		node.Operator = token.Value
+		node.Left = left
+		node.Right = right
+		return node, nil
+	}
+
+	node := new(BinaryExpression)
+	node.Type = BINARY_EXPRESSION
+	node.Operator = token.Value
+	node.Left = left
 	node.Right = right
 	return node, nil
 }
@@ -1014,15 +4339,15 @@ func (self *Parser) parseUnaryExpression(token *Token) (AstNode, error) {
 	node.Prefix = true
 
 	var err error
-	node.Argument, err = self.parseExpressionUntil([]string{"+","-","*","/"})
+	node.Argument, err = self.parseMaybeUnary()
 	if err != nil {
 		return nil, err
 	}
 
-	return node, nil
+	return self.finishNode(node, token.Location), nil
 }
 
-// finishes parsing a unary expression given an operator token
+// finishes parsing a prefix update expression given an operator token
 func (self *Parser) parseUpdateExpression(token *Token) (AstNode, error) {
 	node := new(UpdateExpression)
 	node.Type = UPDATE_EXPRESSION
@@ -1030,59 +4355,74 @@ func (self *Parser) parseUpdateExpression(token *Token) (AstNode, error) {
 	node.Prefix = true
 
 	var err error
-	node.Argument, err = self.parseExpressionUntil([]string{"+","-","*","/"})
+	start := self.startLocation()
+	node.Argument, err = self.parseMaybeUnary()
 	if err != nil {
 		return nil, err
 	}
+	if !_IsSimpleAssignmentTarget(node.Argument) {
+		return nil, NewParseError("invalid left-hand side expression in prefix operation").SetLocation(start)
+	}
 
-	return node, nil
+	return self.finishNode(node, token.Location), nil
 }
 
 // finishes parsing a new expression
 func (self *Parser) parseNewExpression(token *Token) (AstNode, error) {
+	err := self.enterNesting()
+	defer self.leaveNesting()
+	if err != nil {
+		return nil, err
+	}
+
 	node := new(NewExpression)
 	node.Type = NEW_EXPRESSION
+	node.Arguments = []AstNode{}
 
-	nextNode, err := self.parseExpression()
+	nextToken, err := self.peekToken()
+	if err != nil {
+		return nil, err
+	}
+	calleeStart := self.startLocation()
+	if nextToken != nil && nextToken.Value == "new" {
+		_, _ = self.nextToken()
+		node.Callee, err = self.parseNewExpression(nextToken)
+	} else {
+		node.Callee, err = self.parsePrimaryExpression()
+	}
 	if err != nil {
 		return nil, err
 	}
+	if node.Callee.AstType() == IMPORT_EXPRESSION {
+		return nil, NewParseError("cannot use new with import(...)").SetLocation(calleeStart)
+	}
 
-	// todo figure out CallExpression/NewExpression relationship
-	switch nextNode := nextNode.(type) {
-	case *CallExpression:
-		node.Arguments = nextNode.Arguments
-		node.Callee = nextNode.Callee
-		return node, nil
+	// the callee may be a member expression, but the first call belongs to new
+	node.Callee, err = self.parseSubscripts(node.Callee, calleeStart, false)
+	if err != nil {
+		return nil, err
 	}
 
-	// token, err = self.scanner.Peek()
-	// if err != nil {
-	// 	return nil, err
-	// }
-	// if token == nil {
-	// 	perr := NewParseError("cannot parse NEW_EXPRESSION...<<EOF")
-	// 	return nil, perr.SetLocation(self.scanner.Location)
-	// }
-	// if token.Value != "(" {
-	// 	perr := NewParseError("cannot parse NEW_EXPRESSION...<<'%s'(%s)", token.Value, token.Type)
-	// 	return nil, perr.SetLocation(token.Location)
-	// }
-
-	// argList, err := self.parseArgumentList()
-	// if err != nil {
-	// 	return nil, err
-	// }
-	// node.Arguments = argList
+	nextToken, err = self.peekToken()
+	if err != nil {
+		return nil, err
+	}
+	if nextToken != nil && nextToken.Value == "(" {
+		_, _ = self.nextToken()
+		node.Arguments, err = self.parseArgumentList()
+		if err != nil {
+			return nil, err
+		}
+	}
 
-	return node, nil
+	return self.finishNode(node, token.Location), nil
 }
 
 // finishes parsing a this expression
 func (self *Parser) parseThisExpression(token *Token) (AstNode, error) {
 	node := new(ThisExpression)
 	node.Type = THIS_EXPRESSION
-	return node, nil
+	return self.finishNode(node, token.Location), nil
 }
 
 // finishes parsing an identifier
@@ -1090,7 +4430,7 @@ func (self *Parser) parseIdentifier(token *Token) (AstNode, error) {
 	node := new(Identifier)
 	node.Type = IDENTIFIER
 	node.Name = token.Value
-	return node, nil
+	return self.finishNode(node, token.Location), nil
 }
 
 // finishes parsing a literal given a token
@@ -1101,7 +4441,15 @@ func (self *Parser) parseLiteral(token *Token) (AstNode, error) {
 		node.Type = LITERAL
 		node.Value = nil
 		node.Raw = token.Value
-		return node, nil
+		return self.finishNode(node, token.Location), nil
+	}
+
+	if token.Value == "true" || token.Value == "false" {
+		node := new(LiteralBoolean)
+		node.Type = LITERAL
+		node.Value = token.Value == "true"
+		node.Raw = token.Value
+		return self.finishNode(node, token.Location), nil
 	}
 
 	switch token.Type {
@@ -1109,19 +4457,45 @@ func (self *Parser) parseLiteral(token *Token) (AstNode, error) {
 		node := new(LiteralString)
 		node.Type = LITERAL
 		node.Raw = token.Value
-		endPos := len(token.Value) - 1
-		node.Value = token.Value[1:endPos]
-		return node, nil
+		value, err := DecodeStringLiteral(token.Value, token.Location)
+		if err != nil {
+			return nil, self.scannerError(err)
+		}
+		node.Value = value
+		return self.finishNode(node, token.Location), nil
 	case NUMBER:
+		if IsBigIntLiteral(token.Value) {
+			node := new(LiteralBigInt)
+			node.Type = LITERAL
+			node.Raw = token.Value
+			bigint, err := DecodeBigIntLiteral(token.Value, token.Location)
+			if err != nil {
+				return nil, self.scannerError(err)
+			}
+			node.Bigint = bigint
+			return self.finishNode(node, token.Location), nil
+		}
+
 		node := new(LiteralNumber)
 		node.Type = LITERAL
 		node.Raw = token.Value
-		f, err := strconv.ParseFloat(token.Value, 64)
+		f, err := DecodeNumberLiteral(token.Value, token.Location)
 		if err != nil {
-			return nil, err
+			return nil, self.scannerError(err)
 		}
 		node.Value = f
-		return node, nil
+		return self.finishNode(node, token.Location), nil
+	case REGEX:
+		node := new(LiteralRegExp)
+		node.Type = LITERAL
+		node.Raw = token.Value
+		pattern, flags, err := DecodeRegexLiteral(token.Value, token.Location)
+		if err != nil {
+			return nil, self.scannerError(err)
+		}
+		node.Regex.Pattern = pattern
+		node.Regex.Flags = flags
+		return self.finishNode(node, token.Location), nil
 	}
 
 	perr := NewParseError("cannot parse LITERAL<<'%s'(%s)", token.Value, token.Type)
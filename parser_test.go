@@ -4,6 +4,7 @@ import (
 	"os"
 	"fmt"
 	"bufio"
+	"strings"
 	"testing"
 )
 
@@ -37,12 +38,283 @@ func TestParseShapeObjects(raw_t *testing.T) {
 	_RunParserTest("shape-objects", t)
 }
 
+func TestBinaryPrecedence(raw_t *testing.T) {
+	t := NewTestWrapper(raw_t)
+	// t.Trace = true
+	_RunParserTest("binary-precedence", t)
+}
+
+func TestStrings(raw_t *testing.T) {
+	t := NewTestWrapper(raw_t)
+	// t.Trace = true
+	_RunParserTest("strings", t)
+}
+
+func TestNumbers(raw_t *testing.T) {
+	t := NewTestWrapper(raw_t)
+	// t.Trace = true
+	_RunParserTest("numbers", t)
+}
+
+func TestRegex(raw_t *testing.T) {
+	t := NewTestWrapper(raw_t)
+	// t.Trace = true
+	_RunParserTest("regex", t)
+}
+
+func TestTemplates(raw_t *testing.T) {
+	t := NewTestWrapper(raw_t)
+	// t.Trace = true
+	_RunParserTest("templates", t)
+}
+
+func TestLoops(raw_t *testing.T) {
+	t := NewTestWrapper(raw_t)
+	// t.Trace = true
+	_RunParserTest("loops", t)
+}
+
+func TestForInOf(raw_t *testing.T) {
+	t := NewTestWrapper(raw_t)
+	// t.Trace = true
+	_RunParserTest("for-in-of", t)
+}
+
+func TestForAwait(raw_t *testing.T) {
+	t := NewTestWrapper(raw_t)
+	program, err := _ParseWithoutPanic("async function f() { for await (const chunk of stream) read(chunk); }", t)
+	if t.Assert(err == nil, "unexpected error %v", err) {
+		body := program.Body[0].(*FunctionDeclaration).Body.(*BlockStatement)
+		loop, ok := body.Body[0].(*ForOfStatement)
+		if t.Assert(ok, "expected *ForOfStatement, got %#v", body.Body[0]) {
+			t.Assert(loop.Await, "expected await to be set")
+		}
+	}
+	// modules may await at the top level
+	program, err = _ParseWithoutPanicAs(ParseModule, "for await (const chunk of stream) read(chunk);", t)
+	if t.Assert(err == nil, "unexpected error %v", err) {
+		loop, ok := program.Body[0].(*ForOfStatement)
+		if t.Assert(ok, "expected *ForOfStatement, got %#v", program.Body[0]) {
+			t.Assert(loop.Await, "expected await to be set")
+		}
+	}
+}
+
+func TestArrowFunctions(raw_t *testing.T) {
+	t := NewTestWrapper(raw_t)
+	// t.Trace = true
+	_RunParserTest("arrow-functions", t)
+}
+
+func TestArrowFunctionSource(raw_t *testing.T) {
+	t := NewTestWrapper(raw_t)
+	sources := map[string]string{
+		"f(x => x * 2);":                      "x * 2",
+		"f((a, b) => {\n  return a + b;\n});": "  return a + b;",
+		"g(async () => ({ a: 1 }), 2);":       "({ a: 1 })",
+		"h = x => \"\u00e9\U0001F600\";":      "\"\u00e9\U0001F600\"",
+	}
+	for source, expected := range sources {
+		program, err := _ParseWithoutPanic(source, t)
+		if !t.Assert(err == nil, "unexpected error %v for %q", err, source) {
+			continue
+		}
+		var arrow *ArrowFunctionExpression
+		switch expression := program.Body[0].(*ExpressionStatement).Expression.(type) {
+		case *CallExpression:
+			arrow = expression.Arguments[0].(*ArrowFunctionExpression)
+		case *AssignmentExpression:
+			arrow = expression.Right.(*ArrowFunctionExpression)
+		}
+		t.Assert(arrow.Source == expected, "expected source %q for %q, got %q", expected, source, arrow.Source)
+	}
+}
+
+func TestClasses(raw_t *testing.T) {
+	t := NewTestWrapper(raw_t)
+	// t.Trace = true
+	_RunParserTest("classes", t)
+}
+
+func TestPatterns(raw_t *testing.T) {
+	t := NewTestWrapper(raw_t)
+	// t.Trace = true
+	_RunParserTest("patterns", t)
+}
+
+func TestPunctuators(raw_t *testing.T) {
+	t := NewTestWrapper(raw_t)
+	// t.Trace = true
+	_RunParserTest("punctuators", t)
+}
+
+func TestSpreadAndRest(raw_t *testing.T) {
+	t := NewTestWrapper(raw_t)
+	// t.Trace = true
+	_RunParserTest("spread-rest", t)
+}
+
+func TestObjectLiterals(raw_t *testing.T) {
+	t := NewTestWrapper(raw_t)
+	// t.Trace = true
+	_RunParserTest("object-literals", t)
+}
+
+func TestAutomaticSemicolons(raw_t *testing.T) {
+	t := NewTestWrapper(raw_t)
+	// t.Trace = true
+	_RunParserTest("asi", t)
+}
+
+func TestLineTerminators(raw_t *testing.T) {
+	t := NewTestWrapper(raw_t)
+	for _, terminator := range []string{"\n", "\r", "\r\n", "\u2028", "\u2029"} {
+		parser := NewParser(strings.NewReader("a = 1" + terminator + "b = 2 // c" + terminator + "++d"))
+		parser.Loc = true
+		ast, err := parser.Parse()
+		if !t.AssertNoError(err) || !t.AssertEqual(3, len(ast.Body)) {
+			continue
+		}
+		for i, statement := range ast.Body {
+			t.AssertEqual(i+1, statement.(*ExpressionStatement).Loc.Start.Line)
+		}
+
+		ast, err = Parse("function f() { return" + terminator + "x }")
+		if !t.AssertNoError(err) {
+			continue
+		}
+		body := ast.Body[0].(*FunctionDeclaration).Body.(*BlockStatement)
+		if t.AssertEqual(2, len(body.Body)) {
+			t.Assert(body.Body[0].(*ReturnStatement).Argument == nil, "expected %q to end the return statement", terminator)
+		}
+	}
+}
+
+func TestAsyncAndGenerators(raw_t *testing.T) {
+	t := NewTestWrapper(raw_t)
+	// t.Trace = true
+	_RunParserTest("async-generators", t)
+}
+
+func TestComments(raw_t *testing.T) {
+	t := NewTestWrapper(raw_t)
+	// t.Trace = true
+	_RunParserTestWith("comments", t, func(parser *Parser) {
+		parser.Comment = true
+		parser.AttachComment = true
+	})
+}
+
+func TestCommentCollection(raw_t *testing.T) {
+	t := NewTestWrapper(raw_t)
+	source := "a; // one\n/* two\n */ b;"
+
+	ast, err := Parse(source)
+	if t.AssertNoError(err) {
+		t.Assert(ast.Comments == nil, "expected no comments unless asked for")
+	}
+
+	parser := NewParser(strings.NewReader(source))
+	parser.Comment = true
+	parser.Range = true
+	ast, err = parser.Parse()
+	if !t.AssertNoError(err) || !t.AssertEqual(2, len(ast.Comments)) {
+		return
+	}
+	t.AssertEqual(LINE_COMMENT, ast.Comments[0].Type)
+	t.AssertEqual(" one", ast.Comments[0].Value)
+	t.AssertEqual([2]int{3, 9}, *ast.Comments[0].Range)
+	t.AssertEqual(BLOCK_COMMENT, ast.Comments[1].Type)
+	t.AssertEqual(" two\n ", ast.Comments[1].Value)
+	t.AssertEqual([2]int{10, 20}, *ast.Comments[1].Range)
+	t.Assert(ast.Body[0].(*ExpressionStatement).TrailingComments == nil, "expected no attached comments unless asked for")
+}
+
+func TestConditionalAndSequence(raw_t *testing.T) {
+	t := NewTestWrapper(raw_t)
+	// t.Trace = true
+	_RunParserTest("conditional-sequence", t)
+}
+
+func TestModernOperators(raw_t *testing.T) {
+	t := NewTestWrapper(raw_t)
+	// t.Trace = true
+	_RunParserTest("modern-operators", t)
+}
+
+func TestModules(raw_t *testing.T) {
+	t := NewTestWrapper(raw_t)
+	_RunParserTestWith("modules", t, func(parser *Parser) {
+		parser.SourceType = "module"
+	})
+}
+
+func TestLegacyParams(raw_t *testing.T) {
+	t := NewTestWrapper(raw_t)
+	_RunParserTestWith("legacy-params", t, func(parser *Parser) {
+		parser.LegacyParams = true
+	})
+}
+
+func TestSwitchAndTry(raw_t *testing.T) {
+	t := NewTestWrapper(raw_t)
+	// t.Trace = true
+	_RunParserTest("switch-try", t)
+}
+
+func TestIfElse(raw_t *testing.T) {
+	t := NewTestWrapper(raw_t)
+	// t.Trace = true
+	_RunParserTest("if-else", t)
+}
+
+func TestDeclarations(raw_t *testing.T) {
+	t := NewTestWrapper(raw_t)
+	// t.Trace = true
+	_RunParserTest("declarations", t)
+}
+
+func TestLocations(raw_t *testing.T) {
+	t := NewTestWrapper(raw_t)
+	// t.Trace = true
+	_RunParserTestWith("locations", t, func(parser *Parser) {
+		parser.Loc = true
+		parser.Range = true
+	})
+}
+
+func TestLocationsOfSingleNodes(raw_t *testing.T) {
+	t := NewTestWrapper(raw_t)
+
+	parser := NewParser(strings.NewReader("a;\n  b.c(\"😀\");"))
+	parser.Range = true
+	ast, err := parser.Parse()
+	if !t.AssertNoError(err) {
+		return
+	}
+	statement := ast.Body[1].(*ExpressionStatement)
+	t.AssertEqual(&[2]int{5, 15}, statement.Meta().Range)
+	t.Assert(statement.Meta().Loc == nil, "expected no loc without the Loc option")
+	call := statement.Expression.(*CallExpression)
+	t.AssertEqual(&[2]int{5, 14}, call.Meta().Range)
+	t.AssertEqual(&[2]int{9, 13}, call.Arguments[0].(*LiteralString).Meta().Range)
+}
+
 func _RunParserTest(fixture_name string, t *TestWrapper) {
+	_RunParserTestWith(fixture_name, t, nil)
+}
+
+// runs a fixture through a parser with options set by configure
+func _RunParserTestWith(fixture_name string, t *TestWrapper, configure func(*Parser)) {
 	test_input, err := os.Open(fmt.Sprintf("fixtures/%s.js", fixture_name))
 	test_source := bufio.NewReader(test_input)
 	t.AssertNoError(err)
 
-	ast, err := NewParser(test_source).Parse()
+	parser := NewParser(test_source)
+	if configure != nil {
+		configure(parser)
+	}
+	ast, err := parser.Parse()
 	if !t.AssertNoError(err) {
 		return
 	}
@@ -55,3 +327,400 @@ func _RunParserTest(fixture_name string, t *TestWrapper) {
 
 	t.AssertEqualLines(expected_ast, actual_ast)
 }
+
+// inputs that must produce a ParseError rather than an ast or a panic
+var _MalformedSources = []string{
+	"(",
+	")",
+	"))",
+	"a +",
+	"a.",
+	"a[",
+	"a[1",
+	"f(a b)",
+	"f(a,",
+	"new",
+	"var",
+	"var = 1;",
+	"var a = ;",
+	"{a: }",
+	"{a 1}",
+	"{ \"a\" 1 }",
+	"x = [1, 2",
+	"x = [1 2]",
+	"if",
+	"if (a",
+	"for (",
+	"for (var i = 0; i < 1; i++",
+	"for (a in",
+	"(...a);",
+	"(...a, b) => a;",
+	"[...]",
+	"f(...)",
+	"function f(...a = 1) {}",
+	"var [",
+	"var {a",
+	"var {a:}",
+	"var [a b] = c;",
+	"[a] =",
+	"function f([a) {}",
+	"class",
+	"class {}",
+	"class A",
+	"class A {",
+	"class A { a b }",
+	"class A { get a; }",
+	"class A { # }",
+	"#a",
+	"()",
+	"(a, b,)",
+	"a ? b;",
+	"a ? b : ;",
+	"a?.;",
+	"a b",
+	"a /* */ b",
+	"if (a) b else c",
+	"var a = 1 var b",
+	"{\n;",
+	"a\n;\n;[1, 2",
+	"(a,)",
+	"() =>",
+	"async x;",
+	"a + b => c",
+	"(a)\n=> a",
+	"for (a of b, c) ;",
+	"for (var a of b",
+	"function",
+	"function f(",
+	"function f(a b) {}",
+	"function f() {",
+	"function f() { return 1",
+	"(function() {",
+	"a; b c",
+	"\"abc",
+	"/* x",
+	"a @ b",
+	"1.2.3",
+	"++",
+	"typeof",
+	"`abc",
+	"x = `${a",
+	"x = `${}`",
+	"x = `${a b}`",
+	"x = `\\01`",
+	"while (a)",
+	"do x; while",
+	"do x; while (y",
+	"throw",
+	"try {} catch (a, b) {}",
+	"switch (a) { case 1 }",
+	"switch (a) { x; }",
+	"if (a)",
+	"if (a) b(); else",
+	"for (;;)",
+	"for (a b)",
+	"var a,",
+	"let a = 1 b",
+	"x = {[a]};",
+	"x = {\"a\"};",
+	"x = {#a: 1};",
+	"x = {get a: 1};",
+	"x = {async a: 1};",
+	"x = {*a};",
+	"x = import();",
+	"x = import(a, b, c);",
+	"x = import.url;",
+	"function* g() { yield* ; }",
+	"async function f() { await; }",
+	"function *() {}",
+}
+
+func TestMalformedSources(raw_t *testing.T) {
+	t := NewTestWrapper(raw_t)
+	for _, source := range _MalformedSources {
+		ast, err := _ParseWithoutPanic(source, t)
+		if !t.Assert(ast == nil, "expected no ast for %q", source) {
+			continue
+		}
+		_, ok := err.(*ParseError)
+		t.Assert(ok, "expected *ParseError for %q, got %#v", source, err)
+	}
+}
+
+func TestParseErrorLocations(raw_t *testing.T) {
+	t := NewTestWrapper(raw_t)
+	expectations := map[string]Cursor{
+		"var = 1;":            Cursor{0, 4, 4},
+		"f(a b)":              Cursor{0, 4, 4},
+		"x = 1;\n{ \"a\" 1 }": Cursor{1, 6, 13},
+		"a +":                 Cursor{0, 3, 3},
+		"a @ b":               Cursor{0, 2, 2},
+	}
+	for source, location := range expectations {
+		_, err := _ParseWithoutPanic(source, t)
+		perr, ok := err.(*ParseError)
+		if t.Assert(ok, "expected *ParseError for %q, got %#v", source, err) {
+			t.AssertEqual(location, perr.Location)
+		}
+	}
+}
+
+// programs that are syntactically well formed but break an early error rule
+var _EarlyErrors = map[string]string{
+	"break;":                                "illegal break statement",
+	"continue;":                             "illegal continue statement",
+	"while (a) { function f() { break; } }": "illegal break statement",
+	"a: { continue a; }":                    "illegal continue statement: 'a' does not denote an iteration statement",
+	"while (a) { break b; }":                "undefined label 'b'",
+	"x: while (a) { (function() { continue x; }); }": "undefined label 'x'",
+	"a: a: while (b) {}":                             "label 'a' has already been declared",
+	"switch (a) { case 1: continue; }":               "illegal continue statement",
+	"switch (a) { default: default: }":               "more than one default clause in switch statement",
+	"throw\nerror;":                                  "illegal newline after throw",
+	"try {}":                                         "missing catch or finally after try",
+	"const a;":                                       "missing initializer in const declaration",
+	"let a; let a;":                                  "identifier 'a' has already been declared",
+	"var a; let a;":                                  "identifier 'a' has already been declared",
+	"let a; { var a; }":                              "identifier 'a' has already been declared",
+	"function f(a) { let a; }":                       "identifier 'a' has already been declared",
+	"try {} catch (e) { let e; }":                    "identifier 'e' has already been declared",
+	"switch (a) { case 1: let x; case 2: let x; }":   "identifier 'x' has already been declared",
+	"while (a) function f() {}":                      "function declaration cannot appear as the body of a loop",
+	"do function f() {} while (a);":                  "function declaration cannot appear as the body of a loop",
+	"for (;;) async function f() {}":                 "function declaration cannot appear as the body of a loop",
+	"for (a of b) function* g() {}":                  "function declaration cannot appear as the body of a loop",
+	"while (a) l: function f() {}":                   "function declaration cannot appear as the body of a loop",
+	"for (;;) l: m: function f() {}":                 "function declaration cannot appear as the body of a loop",
+	"if (a) l: function f() {}":                      "labelled function declaration cannot appear as the body of an if statement",
+	"if (a) b; else l: function f() {}":              "labelled function declaration cannot appear as the body of an if statement",
+	"if (x) let y = 1;":                              "lexical declaration cannot appear in a single-statement context",
+	"let let = 1;":                                   "let is disallowed as a lexically bound name",
+	"for (var a, b in c) ;":                          "invalid left-hand side in for-in loop: must have a single binding",
+	"for (let a = 1 in b) ;":                         "for-in loop variable declaration may not have an initializer",
+	"for (var a = 1 of b) ;":                         "for-of loop variable declaration may not have an initializer",
+	"for (f() in b) ;":                               "invalid left-hand side in for-in loop",
+	"for (a + 1 of b) ;":                             "invalid left-hand side in for-of loop",
+	"for (let.a of b) ;":                             "the left-hand side of a for-of loop may not be 'let'",
+	"async function f() { for await (a in b) ; }":    "for await is only valid with for-of loops",
+	"async function f() { for await (;;) ; }":        "for await is only valid with for-of loops",
+	"for await (a of b) ;":                           "for await is only valid in async functions and the top level bodies of modules",
+	"function f() { for await (a of b) ; }":          "for await is only valid in async functions and the top level bodies of modules",
+	"async function f() { var await; }":              "'await' may not be used as an identifier here",
+	"function* g() { var yield; }":                   "'yield' may not be used as an identifier here",
+	"function* g() { x = { yield }; }":               "'yield' may not be used as an identifier here",
+	"function* g(a = yield) {}":                      "yield expression may not appear in formal parameters",
+	"async function f(a = await b) {}":               "await expression may not appear in formal parameters",
+	"x = { async m(a = await b) {} };":               "await expression may not appear in formal parameters",
+	"async (await) => 1;":                            "'await' may not be used as an identifier here",
+	"async ({a: await}) => 1;":                       "'await' may not be used as an identifier here",
+	"async await => 1;":                              "'await' may not be used as an identifier here",
+	"(a + 1) => a;":                                  "malformed arrow function parameter list",
+	"(a, a) => a;":                                   "duplicate parameter name not allowed in this context",
+	"new import(\"a\");":                             "cannot use new with import(...)",
+	"new new import(\"a\")();":                       "cannot use new with import(...)",
+	"x => { let x; };":                               "identifier 'x' has already been declared",
+	"while (a) { () => { break; }; }":                "illegal break statement",
+	"class A { constructor() {} constructor() {} }":  "a class may only have one constructor",
+	"class A { get constructor() {} }":               "class constructor may not be an accessor",
+	"class A { constructor = 1; }":                   "classes may not have a field named 'constructor'",
+	"class A { static { await; } }":                  "'await' may not be used as an identifier here",
+	"class A { static { x = () => arguments; } }":    "'arguments' may not be used as an identifier here",
+	"class A { x = arguments; }":                     "'arguments' may not be used as an identifier here",
+	"class A { x = () => arguments[0]; }":            "'arguments' may not be used as an identifier here",
+	"class A { static prototype() {} }":              "classes may not have a static property named 'prototype'",
+	"class A { #constructor() {} }":                  "classes may not have a private field named '#constructor'",
+	"class A { #a; #a; }":                            "identifier '#a' has already been declared",
+	"class A { get #a() {} static set #a(v) {} }":    "identifier '#a' has already been declared",
+	"class A { f() { this.#b; } }":                   "private field '#b' must be declared in an enclosing class",
+	"this.#a;":                                       "private field '#a' must be declared in an enclosing class",
+	"class A { constructor() { super(); } }":         "'super' keyword unexpected here",
+	"class A extends B { f() { super(); } }":         "'super' keyword unexpected here",
+	"class A extends B { constructor() { function f() { super.x; } } }": "'super' keyword unexpected here",
+	"super.x;":                             "'super' keyword unexpected here",
+	"class A { get a(b) {} }":              "getter must not have any formal parameters",
+	"class A { set a() {} }":               "setter must have exactly one formal parameter",
+	"let A; class A {}":                    "identifier 'A' has already been declared",
+	"if (a) class B {}":                    "lexical declaration cannot appear in a single-statement context",
+	"var [a];":                             "missing initializer in destructuring declaration",
+	"let [a, a] = b;":                      "identifier 'a' has already been declared",
+	"function f(a, [a]) {}":                "duplicate parameter name not allowed in this context",
+	"([a], [a]) => 1;":                     "duplicate parameter name not allowed in this context",
+	"([a.b]) => a;":                        "invalid destructuring assignment target",
+	"[a + 1] = b;":                         "invalid destructuring assignment target",
+	"a + 1 = b;":                           "invalid left-hand side in assignment",
+	"f() += 1;":                            "invalid left-hand side in assignment",
+	"var [...a, b] = c;":                   "rest element must be last element",
+	"var {...[a]} = b;":                    "rest property must be followed by an identifier",
+	"for (var [a] = 1 in b) ;":             "for-in loop variable declaration may not have an initializer",
+	"try {} catch ([e, e]) {}":             "identifier 'e' has already been declared",
+	"function f(...a, b) {}":               "rest parameter must be last formal parameter",
+	"async (...a, b) => a;":                "rest parameter must be last formal parameter",
+	"function f(a = 1, a) {}":              "duplicate parameter name not allowed in this context",
+	"[...a, b] = c;":                       "rest element must be last element",
+	"[...a,] = b;":                         "rest element must be last element",
+	"({...a,} = b);":                       "rest element must be last element",
+	"for ([...a,] of b);":                  "rest element must be last element",
+	"([...a,]) => 1;":                      "rest element must be last element",
+	"({...[a]} = c);":                      "rest property must be followed by an identifier",
+	"class A { set a(...b) {} }":           "setter function argument must not be a rest parameter",
+	"for (let a of b) { var a; }":          "identifier 'a' has already been declared",
+	"import a from \"b\";":                 "import declarations may only appear at top level of a module",
+	"export var a;":                        "export declarations may only appear at top level of a module",
+	"import.meta;":                         "cannot use 'import.meta' outside a module",
+	"x = {a = 1};":                         "invalid shorthand property initializer",
+	"f({a = 1});":                          "invalid shorthand property initializer",
+	"for ({a = 1};;) ;":                    "invalid shorthand property initializer",
+	"x = {get a(b) {}};":                   "getter must not have any formal parameters",
+	"x = {set a(...b) {}};":                "setter function argument must not be a rest parameter",
+	"({a() {}} = b);":                      "invalid destructuring assignment target",
+	"({get a() {}} = b);":                  "invalid destructuring assignment target",
+	"class A { async constructor() {} }":   "class constructor may not be an async method",
+	"class A { *constructor() {} }":        "class constructor may not be a generator",
+	"++f();":                               "invalid left-hand side expression in prefix operation",
+	"a() ++;":                              "invalid left-hand side expression in postfix operation",
+	"(a ? b : c) = d;":                     "invalid left-hand side in assignment",
+	"f() &&= 1;":                           "invalid left-hand side in assignment",
+	"a?.b = 1;":                            "invalid left-hand side in assignment",
+	"a?.b++;":                              "invalid left-hand side expression in postfix operation",
+	"new a?.b();":                          "invalid optional chain from new expression",
+	"a?.b`c`;":                             "invalid tagged template on optional chain",
+	"a ?? b || c;":                         "cannot mix '??' and '||' without parentheses",
+	"a || b ?? c;":                         "cannot mix '||' and '??' without parentheses",
+	"a && b ?? c;":                         "cannot mix '&&' and '??' without parentheses",
+	"a ?? b && c;":                         "cannot mix '??' and '&&' without parentheses",
+	"-a ** b;":                             "unary operator used immediately before exponentiation expression",
+	"typeof a ** b;":                       "unary operator used immediately before exponentiation expression",
+	"a ** -b ** c;":                        "unary operator used immediately before exponentiation expression",
+	"async function f() { await a ** 2; }": "unary operator used immediately before exponentiation expression",
+	"(a, b) = c;":                          "invalid left-hand side in assignment",
+}
+
+func TestEarlyErrors(raw_t *testing.T) {
+	t := NewTestWrapper(raw_t)
+	for source, message := range _EarlyErrors {
+		_, err := _ParseWithoutPanic(source, t)
+		perr, ok := err.(*ParseError)
+		if t.Assert(ok, "expected *ParseError for %q, got %#v", source, err) {
+			t.Assert(strings.Contains(perr.Message, message), "expected %q for %q, got %q", message, source, perr.Message)
+		}
+	}
+}
+
+var _ModuleEarlyErrors = map[string]string{
+	"import a, { b as a } from \"m\";":                            "identifier 'a' has already been declared",
+	"import a from \"b\"; let a;":                                 "identifier 'a' has already been declared",
+	"function f() { await; }":                                     "'await' may not be used as an identifier here",
+	"var await;":                                                  "'await' may not be used as an identifier here",
+	"export { a };":                                               "export 'a' is not defined in module",
+	"var a; export { a, a as a };":                                "duplicate export of 'a'",
+	"export default 1; export default 2;":                         "duplicate export of 'default'",
+	"export var a; export function a() {}":                        "identifier 'a' has already been declared",
+	"var a; function a() {}":                                      "identifier 'a' has already been declared",
+	"{ function f() {} function f() {} }":                         "identifier 'f' has already been declared",
+	"function f() {} function f() {}":                             "identifier 'f' has already been declared",
+	"export let a; export { a };":                                 "duplicate export of 'a'",
+	"if (a) { import b from \"c\"; }":                             "import declarations may only appear at top level of a module",
+	"function f() { export var a; }":                              "export declarations may only appear at top level of a module",
+	"import a from \"b\" with { type: \"json\", type: \"css\" };": "import attribute 'type' may only be given once",
+}
+
+func TestModuleEarlyErrors(raw_t *testing.T) {
+	t := NewTestWrapper(raw_t)
+	for source, message := range _ModuleEarlyErrors {
+		_, err := _ParseWithoutPanicAs(ParseModule, source, t)
+		perr, ok := err.(*ParseError)
+		if t.Assert(ok, "expected *ParseError for %q, got %#v", source, err) {
+			t.Assert(strings.Contains(perr.Message, message), "expected %q for %q, got %q", message, source, perr.Message)
+		}
+	}
+}
+
+func TestModuleSourcesInScripts(raw_t *testing.T) {
+	t := NewTestWrapper(raw_t)
+	ast, err := _ParseWithoutPanic("import(\"a\").then(f);", t)
+	if t.AssertNoError(err) {
+		t.AssertEqual("script", ast.SourceType)
+	}
+	ast, err = _ParseWithoutPanicAs(ParseModule, "import.meta.url;", t)
+	if t.AssertNoError(err) {
+		t.AssertEqual("module", ast.SourceType)
+	}
+}
+
+func TestDeepNesting(raw_t *testing.T) {
+	t := NewTestWrapper(raw_t)
+	sources := []string{
+		strings.Repeat("(", 1000000),
+		strings.Repeat("[", 1000000),
+		strings.Repeat("- ", 1000000) + "a",
+		strings.Repeat("a = ", 1000000) + "b",
+		strings.Repeat("a ** ", 1000000) + "b",
+		strings.Repeat("new ", 1000000) + "a",
+		"var " + strings.Repeat("[", 1000000),
+		"var " + strings.Repeat("{a:", 1000000),
+		"let " + strings.Repeat("[", 1000000),
+		"x = " + strings.Repeat("class extends ", 1000000) + "a {}",
+	}
+	for _, source := range sources {
+		_, err := _ParseWithoutPanic(source, t)
+		_, ok := err.(*ParseError)
+		if !t.Assert(ok, "expected *ParseError for deeply nested source, got %#v", err) {
+			continue
+		}
+		t.Assert(strings.Contains(err.Error(), "maximum nesting depth exceeded"), "expected nesting error for deeply nested source, got %s", err)
+	}
+}
+
+func FuzzParse(f *testing.F) {
+	for _, source := range _MalformedSources {
+		f.Add(source)
+	}
+	for _, fixture_name := range []string{"arrays", "arrow-functions", "asi", "async-generators", "basic-parse", "binary-precedence", "classes", "comments", "conditional-sequence", "declarations", "exported-constants", "for-in-of", "if-else", "legacy-params", "locations", "loops", "modern-operators", "modules", "negatives", "numbers", "object-literals", "patterns", "punctuators", "regex", "shape-objects", "spread-rest", "strings", "switch-try", "templates"} {
+		source, err := os.ReadFile(fmt.Sprintf("fixtures/%s.js", fixture_name))
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(string(source))
+	}
+	f.Fuzz(func(raw_t *testing.T, source string) {
+		for _, parse := range []func(string) (*Program, error){Parse, ParseModule, _ParseWithComments} {
+			_, err := parse(source)
+			if err != nil {
+				if _, ok := err.(*ParseError); !ok {
+					raw_t.Errorf("expected *ParseError for %q, got %#v", source, err)
+				}
+			}
+		}
+		_, err := Tokenize(source, TokenizeOptions{Range: true, Loc: true, Comment: true})
+		if err != nil {
+			if _, ok := err.(*ParseError); !ok {
+				raw_t.Errorf("expected *ParseError tokenizing %q, got %#v", source, err)
+			}
+		}
+	})
+}
+
+// parses source with every comment collected and attached, and with locations
+func _ParseWithComments(source string) (*Program, error) {
+	parser := NewParser(strings.NewReader(source))
+	parser.Loc = true
+	parser.Range = true
+	parser.Comment = true
+	parser.AttachComment = true
+	return parser.Parse()
+}
+
+// parses source, reporting a panic as a test failure
+func _ParseWithoutPanic(source string, t *TestWrapper) (ast *Program, err error) {
+	return _ParseWithoutPanicAs(Parse, source, t)
+}
+
+// parses source with parse, such as ParseModule, reporting a panic as a test failure
+func _ParseWithoutPanicAs(parse func(string) (*Program, error), source string, t *TestWrapper) (ast *Program, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			t.Assert(false, "parser panicked on %q: %v", source, r)
+		}
+	}()
+	return parse(source)
+}
@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"io"
 	"bytes"
+	"strings"
+	"unicode/utf16"
 )
 
 // a scanner of tokens
@@ -15,12 +17,91 @@ type TokenScanner struct {
 	unToken   *Token
 	capture   *SourceCapture
 	Trace     bool
+	// a token split from the end of the last one, scanned next
+	pending *Token
+
+	// context for telling regular expressions from division
+	lastSignificant *Token
+	// the significant token before the last, telling if a word is a property name
+	prevSignificant *Token
+	parenStack      []_ParenContext
+	braceStack      []_BraceContext
+	closedParen     _ParenContext
+	closedBlock     bool
+	// function and class expressions whose body has not opened yet
+	expressionBodies []_ExpressionBody
+	// whether the last async keyword starts an expression
+	asyncExpression bool
+	// the functions whose bodies are open, telling if yield and await are keywords
+	functions []_FunctionScope
+	// the kind of function whose params the next parenthesis may open,
+	// after a function keyword or the modifiers of a method
+	nextFunction _FunctionKind
+	// the kind of arrow function whose body follows the last =>
+	arrow *_FunctionKind
+
+	// a line terminator was scanned since the last significant token
+	sawNewline bool
+	// the last rune read, as a \n after \r does not start another line
+	lastRune rune
+}
+
+// what an open brace started, for telling how its closing brace continues
+type _BraceContext int
+
+const (
+	_BRACE_OBJECT _BraceContext = iota
+	_BRACE_BLOCK
+	_BRACE_TEMPLATE
+	// the body of a function or class expression, which the expression continues after
+	_BRACE_EXPRESSION_BODY
+)
+
+// what an open parenthesis started, for telling how its closing parenthesis continues
+type _ParenContext struct {
+	// the condition of a statement such as if, which a statement follows
+	condition bool
+	// the head of a statement such as switch, which a block follows
+	statement bool
+	// the head of a for statement, where of is a keyword
+	forHead bool
+	// opened right after async, so may be the params of an async arrow function
+	async bool
+	// the kind of function whose params it is, if a body follows
+	function _FunctionKind
+	// the number of braces open around it
+	braces int
+}
+
+// whether a function is a generator, where yield is a keyword, or async, where await is
+type _FunctionKind struct {
+	generator bool
+	async     bool
+}
+
+// a function whose body is open, by the number of
+// parentheses and braces open inside its body
+type _FunctionScope struct {
+	kind   _FunctionKind
+	parens int
+	braces int
+	// an arrow function body without braces, which ends with its expression
+	concise bool
+}
+
+// where the body of a function or class expression opens,
+// by the number of parentheses and braces open around it
+type _ExpressionBody struct {
+	parens int
+	braces int
 }
 
 // location within the source input
+// lines are counted from 0, columns and offsets in UTF-16 code units like javascript string indices
 type Cursor struct {
-	line   int
-	column int
+	Line   int
+	Column int
+	Offset int
 }
 
 // used for capturing source blocks like functions
@@ -33,19 +114,33 @@ type SourceCapture struct {
 func NewTokenScanner(input io.RuneScanner) *TokenScanner {
 	ts := new(TokenScanner)
 	ts.input = input
-	ts.Location = Cursor{0, 0}
+	ts.Location = Cursor{0, 0, 0}
 	return ts
 }
 
 func (self *Cursor) _IncrementByRune(r rune) {
-	if r == '\n' {
-		self.line += 1
-		self.column = 0
+	width := utf16.RuneLen(r)
+	if width < 1 {
+		width = 1
+	}
+	self.Offset += width
+	if IsLineTerminatorRune(r) {
+		self.Line += 1
+		self.Column = 0
 	} else {
-		self.column += 1
+		self.Column += width
 	}
 }
 
+// advances the cursor past r, which follows prev, counting \r\n as one line terminator
+func (self *Cursor) _IncrementAfterRune(prev rune, r rune) {
+	if prev == '\r' && r == '\n' {
+		self.Offset += 1
+		return
+	}
+	self._IncrementByRune(r)
+}
+
 func (self *SourceCapture) WriteRune(r rune) {
 	_, err := self.buf.WriteRune(r)
 	if err != nil {
@@ -60,6 +155,18 @@ func (self SourceCapture) String() string {
 	return string(self.buf.Bytes())
 }
 
+// the start of the capture, up to a length in UTF-16 code units like cursor offsets
+func (self SourceCapture) Prefix(length int) string {
+	s := self.String()
+	for i, r := range s {
+		if length <= 0 {
+			return s[:i]
+		}
+		length -= utf16.RuneLen(r)
+	}
+	return s
+}
+
 // begins a capture of a source block
 func (self *TokenScanner) BeginCapture() {
 	sc := new(SourceCapture)
@@ -91,7 +198,41 @@ func (self *TokenScanner) Next() (*Token, error) {
 		return token, nil
 	}
 
-	// scan
+	token, err := self.scan(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if self.Trace {
+		fmt.Printf("\x1b[90m%v\x1b[0m\n", token)
+	}
+
+	// cache last token
+	self.lastToken = token
+	return token, nil
+}
+
+// continues scanning a "/" or "/=" token as a regular expression literal,
+// for when the parser expects an expression where the scanner saw division
+func (self *TokenScanner) RescanAsRegex(token *Token) (*Token, error) {
+	if self.unToken != nil || token != self.lastToken {
+		return nil, ScannerError{"can only rescan the last token as a regular expression"}
+	}
+	token.Type = _REGEX
+	token, err := self.scan(token)
+	if err != nil {
+		return nil, err
+	}
+	self.lastToken = token
+	return token, nil
+}
+
+// reads runes into token until it is complete, starting a new token if nil
+func (self *TokenScanner) scan(token *Token) (*Token, error) {
+	if token == nil && self.pending != nil {
+		token = self.pending
+		self.pending = nil
+	}
 	for {
 		r, rlen, err := self.input.ReadRune()
 		if err != nil {
@@ -104,22 +245,41 @@ func (self *TokenScanner) Next() (*Token, error) {
 		if token == nil {
 			token = new(Token)
 			token.Location = self.Location
+			if r == '}' && self.inTemplateSubstitution() {
+				// the closing brace of a substitution continues its template
+				token.Type = _TEMPLATE
+			}
 		}
 
 		ok, sntxErr := token.ConsumeRune(r)
+		if sntxErr == nil && !ok && token.Type == _ONE_SLASH {
+			// not a comment, so either division or a regular expression
+			if self.regexAllowed() {
+				token.Type = _REGEX
+			} else {
+				token.Type = OPERATOR
+			}
+			ok, sntxErr = token.ConsumeRune(r)
+		}
 		if sntxErr != nil {
 			sntxErr.Location = self.Location
 			return nil, sntxErr
 		}
 		if ok {
 			self.offset += int64(rlen)
-			self.Location._IncrementByRune(r)
+			self.Location._IncrementAfterRune(self.lastRune, r)
+			self.lastRune = r
 			if self.capture != nil {
 				self.capture.WriteRune(r)
 			}
 			continue
 		}
 
+		if token.Type == OPERATOR && token.Value == "?." && IsDigitRune(r) {
+			// a conditional followed by a number, as in a?.5:1
+			token.Value = "?"
+			self.pending = &Token{OPERATOR, token.End(), ".", false}
+		}
 		self.input.UnreadRune()
 		if _SPACE != token.Type {
 			break
@@ -127,30 +287,413 @@ func (self *TokenScanner) Next() (*Token, error) {
 		token = nil
 	}
 
-	// correct type of single line comment at eof
+	// correct types of tokens completed by eof
+	if token != nil && _SPACE == token.Type {
+		token = nil
+	}
 	if token != nil && _COMMENT_SINGLE_LINE == token.Type {
 		token.Type = COMMENT
 	}
+	if token != nil && _ONE_SLASH == token.Type {
+		token.Type = OPERATOR
+	}
 
 	// check for internal enums
 	if token != nil && token.Type >= _HIDDEN {
-		if token.Type == _COMMENT_MULTI_LINE ||
-			token.Type == _COMMENT_MULTI_LINE_MAY_END {
+		switch token.Type {
+		case _COMMENT_MULTI_LINE, _COMMENT_MULTI_LINE_MAY_END:
 			return nil, &SyntaxError{"incomplete multiline comment", token.Location}
-		} else {
+		case _STRING_SINGLE_QUOTE, _STRING_DOUBLE_QUOTE,
+			_STRING_SINGLE_QUOTE_ESCAPE, _STRING_DOUBLE_QUOTE_ESCAPE:
+			return nil, &SyntaxError{"unterminated string literal", token.Location}
+		case _REGEX, _REGEX_ESCAPE, _REGEX_CLASS, _REGEX_CLASS_ESCAPE:
+			return nil, &SyntaxError{"unterminated regular expression", token.Location}
+		case _TEMPLATE, _TEMPLATE_ESCAPE, _TEMPLATE_DOLLAR:
+			return nil, &SyntaxError{"unterminated template literal", token.Location}
+		case _HASH:
+			return nil, &SyntaxError{"invalid private name", token.Location}
+		default:
 			return nil, &SyntaxError{"unexpected eof", self.Location}
 		}
 	}
 
-	if self.Trace {
-		fmt.Printf("\x1b[90m%v\x1b[0m\n", token)
+	// check escape sequences
+	if token != nil && token.Type == STRING {
+		_, sntxErr := DecodeStringLiteral(token.Value, token.Location)
+		if sntxErr != nil {
+			return nil, sntxErr
+		}
 	}
 
-	// cache last token
-	self.lastToken = token
+	// check numeric literal syntax
+	if token != nil && token.Type == NUMBER {
+		var sntxErr *SyntaxError
+		if IsBigIntLiteral(token.Value) {
+			_, sntxErr = DecodeBigIntLiteral(token.Value, token.Location)
+		} else {
+			_, sntxErr = DecodeNumberLiteral(token.Value, token.Location)
+		}
+		if sntxErr != nil {
+			return nil, sntxErr
+		}
+	}
+
+	// check regular expression flags
+	if token != nil && token.Type == REGEX {
+		_, _, sntxErr := DecodeRegexLiteral(token.Value, token.Location)
+		if sntxErr != nil {
+			return nil, sntxErr
+		}
+	}
+
+	if token != nil && token.Type != COMMENT && token.Type != NEWLINE {
+		self.trackContext(token)
+		// a rescanned token keeps the flag it was first scanned with
+		token.NewlineBefore = token.NewlineBefore || self.sawNewline
+		self.sawNewline = false
+	}
+	if token != nil && (token.Type == NEWLINE || (token.Type == COMMENT && strings.ContainsAny(token.Value, "\n\r\u2028\u2029"))) {
+		self.sawNewline = true
+	}
 	return token, nil
 }
 
+// records a significant token, for deciding if a following slash starts a regular expression
+func (self *TokenScanner) trackContext(token *Token) {
+	prev := self.lastSignificant
+	if prev != nil && prev.Type == ATOM && prev.Value == "async" && self.sawNewline {
+		// a line terminator ends an async modifier
+		self.nextFunction.async = false
+	}
+	if self.arrow != nil && token.Value != "{" {
+		self.functions = append(self.functions, _FunctionScope{*self.arrow, len(self.parenStack), len(self.braceStack), true})
+	}
+	if token.Value == "," || token.Value == ";" {
+		self.endConciseBodies()
+	}
+
+	if token.Type == DELIMITER {
+		switch token.Value {
+		case "(":
+			self.parenStack = append(self.parenStack, self.openParen())
+		case ")":
+			self.closedParen = _ParenContext{}
+			if n := len(self.parenStack); n > 0 {
+				self.closedParen = self.parenStack[n-1]
+				self.parenStack = self.parenStack[:n-1]
+			}
+			self.dropExpressionBodies()
+			self.dropFunctions()
+		case "{":
+			context := _BRACE_OBJECT
+			if self.opensExpressionBody() {
+				context = _BRACE_EXPRESSION_BODY
+			} else if self.braceIsBlock() {
+				context = _BRACE_BLOCK
+			}
+			self.braceStack = append(self.braceStack, context)
+			self.openFunctionBody()
+		case "}":
+			self.closedBlock = true
+			if n := len(self.braceStack); n > 0 {
+				self.closedBlock = self.braceStack[n-1] == _BRACE_BLOCK
+				self.braceStack = self.braceStack[:n-1]
+			}
+			self.dropExpressionBodies()
+			self.dropFunctions()
+		}
+	}
+	self.arrow = nil
+	if token.Type == ATOM && !self.followsDot() {
+		switch token.Value {
+		case "async":
+			self.asyncExpression = self.keywordStartsExpression()
+			self.nextFunction.async = true
+		case "function", "class":
+			if self.keywordStartsExpression() {
+				self.expressionBodies = append(self.expressionBodies, _ExpressionBody{len(self.parenStack), len(self.braceStack)})
+			}
+			if token.Value == "function" {
+				async := prev != nil && prev.Type == ATOM && prev.Value == "async" && !self.sawNewline && !self.lastIsPropertyName()
+				self.nextFunction = _FunctionKind{false, async}
+			}
+		}
+	}
+	if token.Type == OPERATOR || token.Type == DELIMITER {
+		self.trackFunctionModifier(token)
+	}
+	if token.Type == TEMPLATE {
+		if strings.HasPrefix(token.Value, "}") {
+			if n := len(self.braceStack); n > 0 {
+				self.braceStack = self.braceStack[:n-1]
+			}
+			self.dropExpressionBodies()
+			self.dropFunctions()
+		}
+		if strings.HasSuffix(token.Value, "${") {
+			self.braceStack = append(self.braceStack, _BRACE_TEMPLATE)
+		}
+	}
+	self.prevSignificant = self.lastSignificant
+	self.lastSignificant = token
+}
+
+// the context of a parenthesis opened after the last significant token
+func (self *TokenScanner) openParen() _ParenContext {
+	context := _ParenContext{braces: len(self.braceStack), function: self.nextFunction}
+	self.nextFunction = _FunctionKind{}
+
+	prev := self.lastSignificant
+	if prev == nil || prev.Type != ATOM || self.lastIsPropertyName() {
+		return context
+	}
+	before := self.prevSignificant
+	switch {
+	case IsKeywordBeforeCondition(prev.Value):
+		context.condition = true
+		context.forHead = prev.Value == "for"
+	case prev.Value == "await" && before != nil && before.Type == ATOM && before.Value == "for":
+		// for await (...)
+		context.condition = true
+		context.forHead = true
+	case prev.Value == "switch" || prev.Value == "catch":
+		context.statement = true
+	case prev.Value == "async" && !self.sawNewline:
+		// async (...) => {}, or a method named async
+		context.async = true
+		context.function.async = false
+	}
+	return context
+}
+
+// records the generator star and arrow of functions, and forgets async modifiers
+// that turned out to be names
+func (self *TokenScanner) trackFunctionModifier(token *Token) {
+	prev := self.lastSignificant
+	switch token.Value {
+	case "*":
+		if prev == nil || self.lastIsPropertyName() {
+			return
+		}
+		switch prev.Value {
+		case "function", "async", "static", "{", ",", ";", "}":
+			// function* () {}, or a generator method
+			self.nextFunction.generator = true
+		}
+	case "=>":
+		arrow := _FunctionKind{}
+		if prev != nil && prev.Value == ")" {
+			arrow.async = self.closedParen.async
+		} else if before := self.prevSignificant; before != nil && before.Type == ATOM && before.Value == "async" {
+			// async x => ...
+			arrow.async = true
+		}
+		self.arrow = &arrow
+		self.nextFunction = _FunctionKind{}
+	case "(", ".", "?.", "[", "]":
+	default:
+		self.nextFunction = _FunctionKind{}
+	}
+}
+
+// records a function body opened by the last brace, after the params of a function
+// or method, or the arrow of an arrow function
+func (self *TokenScanner) openFunctionBody() {
+	var kind _FunctionKind
+	prev := self.lastSignificant
+	switch {
+	case self.arrow != nil:
+		kind = *self.arrow
+	case prev != nil && prev.Type == DELIMITER && prev.Value == ")" && !self.closedParen.condition && !self.closedParen.statement:
+		kind = self.closedParen.function
+	default:
+		return
+	}
+	self.functions = append(self.functions, _FunctionScope{kind, len(self.parenStack), len(self.braceStack), false})
+}
+
+// forgets functions whose bodies a closed parenthesis or brace ended
+func (self *TokenScanner) dropFunctions() {
+	for n := len(self.functions); n > 0; n-- {
+		scope := self.functions[n-1]
+		if scope.parens <= len(self.parenStack) && scope.braces <= len(self.braceStack) {
+			break
+		}
+		self.functions = self.functions[:n-1]
+	}
+}
+
+// forgets arrow functions whose bodies without braces a comma or semicolon ended
+func (self *TokenScanner) endConciseBodies() {
+	for n := len(self.functions); n > 0; n-- {
+		scope := self.functions[n-1]
+		if !scope.concise || scope.parens != len(self.parenStack) || scope.braces != len(self.braceStack) {
+			break
+		}
+		self.functions = self.functions[:n-1]
+	}
+}
+
+// the kind of the innermost function whose body is open
+func (self *TokenScanner) innerFunction() _FunctionKind {
+	if n := len(self.functions); n > 0 {
+		return self.functions[n-1].kind
+	}
+	return _FunctionKind{}
+}
+
+// whether the last significant token is a keyword followed by an expression,
+// deciding contextual keywords by where they appear
+func (self *TokenScanner) lastIsKeywordBeforeExpression() bool {
+	last := self.lastSignificant
+	if self.lastIsPropertyName() {
+		return false
+	}
+	switch last.Value {
+	case "of":
+		// for (x of ...), but not for (of of ...) or for (var of ...)
+		n := len(self.parenStack)
+		if n == 0 || !self.parenStack[n-1].forHead || self.parenStack[n-1].braces != len(self.braceStack) {
+			return false
+		}
+		before := self.prevSignificant
+		if before == nil {
+			return false
+		}
+		switch before.Type {
+		case ATOM:
+			return !IsKeyword(before.Value)
+		case DELIMITER:
+			return before.Value == "]" || before.Value == "}"
+		}
+		return false
+	case "yield":
+		return self.innerFunction().generator
+	case "await":
+		return self.innerFunction().async
+	}
+	return IsKeywordBeforeExpression(last.Value)
+}
+
+// whether the last significant token is a name following a dot, as in a.in,
+// so is not a keyword
+func (self *TokenScanner) lastIsPropertyName() bool {
+	prev := self.prevSignificant
+	return prev != nil && prev.Type == OPERATOR && (prev.Value == "." || prev.Value == "?.")
+}
+
+// whether the last significant token is a dot, so the name following it is a property name
+func (self *TokenScanner) followsDot() bool {
+	last := self.lastSignificant
+	return last != nil && last.Type == OPERATOR && (last.Value == "." || last.Value == "?.")
+}
+
+// whether a function, class or async keyword after the last significant token
+// starts an expression rather than a declaration
+func (self *TokenScanner) keywordStartsExpression() bool {
+	prev := self.lastSignificant
+	if prev == nil {
+		return false
+	}
+	if prev.Type == ATOM && prev.Value == "async" {
+		return self.asyncExpression
+	}
+	if prev.Value == "=>" {
+		return true
+	}
+	// where a brace would start an object literal, an expression is expected
+	return !self.braceIsBlock()
+}
+
+// whether an opening brace is the body of the innermost function or class expression,
+// being the first brace opened beside its keyword
+func (self *TokenScanner) opensExpressionBody() bool {
+	n := len(self.expressionBodies)
+	if n == 0 {
+		return false
+	}
+	body := self.expressionBodies[n-1]
+	if body.parens != len(self.parenStack) || body.braces != len(self.braceStack) {
+		return false
+	}
+	self.expressionBodies = self.expressionBodies[:n-1]
+	return true
+}
+
+// forgets function and class expressions enclosed by a closed parenthesis or brace,
+// such as a property named function, which never open a body
+func (self *TokenScanner) dropExpressionBodies() {
+	for n := len(self.expressionBodies); n > 0; n-- {
+		body := self.expressionBodies[n-1]
+		if body.parens <= len(self.parenStack) && body.braces <= len(self.braceStack) {
+			break
+		}
+		self.expressionBodies = self.expressionBodies[:n-1]
+	}
+}
+
+// whether the innermost open brace is a template substitution
+func (self *TokenScanner) inTemplateSubstitution() bool {
+	n := len(self.braceStack)
+	return n > 0 && self.braceStack[n-1] == _BRACE_TEMPLATE
+}
+
+// whether a slash after the last significant token starts a regular expression rather than division
+func (self *TokenScanner) regexAllowed() bool {
+	prev := self.lastSignificant
+	if prev == nil {
+		return true
+	}
+
+	switch prev.Type {
+	case NUMBER, STRING, REGEX, PRIVATE_NAME:
+		return false
+	case TEMPLATE:
+		// a substitution starts an expression
+		return strings.HasSuffix(prev.Value, "${")
+	case ATOM:
+		return self.lastIsKeywordBeforeExpression()
+	}
+
+	switch prev.Value {
+	case ")":
+		// if (...) /re/
+		return self.closedParen.condition
+	case "]", "++", "--":
+		return false
+	case "}":
+		// blocks end statements, object literals end expressions
+		return self.closedBlock
+	}
+	return true
+}
+
+// whether an opening brace after the last significant token starts a block rather than an object literal
+func (self *TokenScanner) braceIsBlock() bool {
+	prev := self.lastSignificant
+	if prev == nil {
+		return true
+	}
+
+	switch prev.Type {
+	case ATOM:
+		if prev.Value == "do" || prev.Value == "else" {
+			return true
+		}
+		return !self.lastIsKeywordBeforeExpression()
+	case NUMBER, STRING, REGEX:
+		return true
+	case TEMPLATE:
+		return !strings.HasSuffix(prev.Value, "${")
+	}
+
+	switch prev.Value {
+	case ";", "{", "}", ")", "=>":
+		return true
+	}
+	return false
+}
+
 // moves the scanner back one. cannot go back more than one.
 func (self *TokenScanner) UnNext() error {
 	if self.unToken != nil {
@@ -183,14 +726,18 @@ func (self *Token) ConsumeRune(r rune) (bool, *SyntaxError) {
 		switch {
 		case IsInlineWhitespaceRune(r):
 			self.Type = _SPACE
-		case '\n' == r:
+		case IsLineTerminatorRune(r):
 			self.Type = NEWLINE
-		// case '\'' == r:
-		// 	self.Type = _STRING_SINGLE_QUOTE
+		case '\'' == r:
+			self.Type = _STRING_SINGLE_QUOTE
 		case '"' == r:
 			self.Type = _STRING_DOUBLE_QUOTE
 		case '/' == r:
 			self.Type = _ONE_SLASH
+		case '`' == r:
+			self.Type = _TEMPLATE
+		case '#' == r:
+			self.Type = _HASH
 		case IsDelimeterRune(r):
 			self.Type = DELIMITER
 		case IsOperatorRune(r):
@@ -200,7 +747,7 @@ func (self *Token) ConsumeRune(r rune) (bool, *SyntaxError) {
 		case IsDigitRune(r):
 			self.Type = NUMBER
 		default:
-			return false, &SyntaxError{fmt.Sprintf("Invalid Rune %c", r), Cursor{-1, -1}}
+			return false, &SyntaxError{fmt.Sprintf("Invalid Rune %c", r), Cursor{-1, -1, -1}}
 		}
 		self.Value += string(r)
 		return true, nil
@@ -211,6 +758,12 @@ func (self *Token) ConsumeRune(r rune) (bool, *SyntaxError) {
 			self.Value += string(r)
 			return true, nil
 		}
+	case NEWLINE:
+		// \r\n is a single line terminator
+		if r == '\n' && self.Value == "\r" {
+			self.Value += string(r)
+			return true, nil
+		}
 	case _ONE_SLASH:
 		if r == '/' {
 			self.Value += string(r)
@@ -223,7 +776,7 @@ func (self *Token) ConsumeRune(r rune) (bool, *SyntaxError) {
 			return true, nil
 		}
 	case _COMMENT_SINGLE_LINE:
-		if r == '\n' {
+		if IsLineTerminatorRune(r) {
 			self.Type = COMMENT
 			return false, nil
 		} else {
@@ -248,12 +801,86 @@ func (self *Token) ConsumeRune(r rune) (bool, *SyntaxError) {
 		self.Value += string(r)
 		return true, nil
 
-	case _STRING_DOUBLE_QUOTE:
-		self.Value += string(r)
-		if r == '"' {
+	case _STRING_SINGLE_QUOTE, _STRING_DOUBLE_QUOTE:
+		switch {
+		case r == '\\':
+			if self.Type == _STRING_SINGLE_QUOTE {
+				self.Type = _STRING_SINGLE_QUOTE_ESCAPE
+			} else {
+				self.Type = _STRING_DOUBLE_QUOTE_ESCAPE
+			}
+		case r == '\n' && strings.HasSuffix(self.Value, "\r"):
+			// \r\n line continuation, the \r was escaped
+		case r == '\n' || r == '\r':
+			return false, &SyntaxError{"unterminated string literal", Cursor{-1, -1, -1}}
+		case r == '\'' && self.Type == _STRING_SINGLE_QUOTE:
+			self.Type = STRING
+		case r == '"' && self.Type == _STRING_DOUBLE_QUOTE:
 			self.Type = STRING
 		}
+		self.Value += string(r)
+		return true, nil
+	case _STRING_SINGLE_QUOTE_ESCAPE:
+		self.Value += string(r)
+		self.Type = _STRING_SINGLE_QUOTE
+		return true, nil
+	case _STRING_DOUBLE_QUOTE_ESCAPE:
+		self.Value += string(r)
+		self.Type = _STRING_DOUBLE_QUOTE
+		return true, nil
+
+	case _REGEX, _REGEX_ESCAPE, _REGEX_CLASS, _REGEX_CLASS_ESCAPE:
+		if IsLineTerminatorRune(r) {
+			return false, &SyntaxError{"unterminated regular expression", Cursor{-1, -1, -1}}
+		}
+		switch {
+		case self.Type == _REGEX_ESCAPE:
+			self.Type = _REGEX
+		case self.Type == _REGEX_CLASS_ESCAPE:
+			self.Type = _REGEX_CLASS
+		case r == '\\' && self.Type == _REGEX:
+			self.Type = _REGEX_ESCAPE
+		case r == '\\' && self.Type == _REGEX_CLASS:
+			self.Type = _REGEX_CLASS_ESCAPE
+		case r == '[' && self.Type == _REGEX:
+			self.Type = _REGEX_CLASS
+		case r == ']' && self.Type == _REGEX_CLASS:
+			self.Type = _REGEX
+		case r == '/' && self.Type == _REGEX:
+			self.Type = REGEX
+		}
+		self.Value += string(r)
+		return true, nil
+	case REGEX:
+		// flags
+		if IsAtomRune(r) || IsDigitRune(r) {
+			self.Value += string(r)
+			return true, nil
+		}
+		return false, nil
+
+	case _TEMPLATE, _TEMPLATE_DOLLAR:
+		// ends at the closing backtick or the start of a substitution
+		switch {
+		case r == '\\':
+			self.Type = _TEMPLATE_ESCAPE
+		case r == '`':
+			self.Type = TEMPLATE
+		case r == '{' && self.Type == _TEMPLATE_DOLLAR:
+			self.Type = TEMPLATE
+		case r == '$':
+			self.Type = _TEMPLATE_DOLLAR
+		default:
+			self.Type = _TEMPLATE
+		}
+		self.Value += string(r)
+		return true, nil
+	case _TEMPLATE_ESCAPE:
+		self.Value += string(r)
+		self.Type = _TEMPLATE
 		return true, nil
+	case TEMPLATE:
+		return false, nil
 
 	case COMMENT:
 		return false, nil
@@ -262,20 +889,41 @@ func (self *Token) ConsumeRune(r rune) (bool, *SyntaxError) {
 	case STRING:
 		return false, nil
 	case OPERATOR:
-		if IsOperatorRune(r) {
+		if self.Value == "." && r >= '0' && r <= '9' {
+			// number with leading decimal point
 			self.Value += string(r)
+			self.Type = NUMBER
 			return true, nil
 		}
-	case ATOM:
-		if IsAtomRune(r) || IsDigitRune(r) {
+		// the longest punctuator wins, so a=-1 is = followed by -
+		if IsOperatorRune(r) && IsPunctuatorPrefix(self.Value+string(r)) {
 			self.Value += string(r)
 			return true, nil
 		}
-	case NUMBER:
-		if r == '.' || IsDigitRune(r) {
+	case ATOM, PRIVATE_NAME:
+		if IsAtomRune(r) || IsDigitRune(r) {
 			self.Value += string(r)
 			return true, nil
 		}
+	case _HASH:
+		// a private name, such as #field in a class body
+		if !IsAtomRune(r) {
+			return false, &SyntaxError{"invalid private name", Cursor{-1, -1, -1}}
+		}
+		self.Value += string(r)
+		self.Type = PRIVATE_NAME
+		return true, nil
+	case NUMBER:
+		// accepts anything that may continue a numeric literal, checked once complete
+		switch {
+		case IsDigitRune(r) || IsAtomRune(r):
+		case r == '.' && _NumberMayContinueWithDot(self.Value):
+		case (r == '+' || r == '-') && _NumberMayContinueWithSign(self.Value):
+		default:
+			return false, nil
+		}
+		self.Value += string(r)
+		return true, nil
 
 	}
 	return false, nil
@@ -3,6 +3,7 @@ package jaess
 import (
 	// "fmt"
 	"bufio"
+	"encoding/json"
 	"os"
 	"strings"
 	"testing"
@@ -21,17 +22,17 @@ func TestBasicScanning(raw_t *testing.T) {
 
 	token, err = scanner.Next()
 	t.AssertNoError(err)
-	t.AssertEqual(Token{ATOM, Cursor{0, 0}, "anatøm"}, *token)
+	t.AssertEqual(Token{ATOM, Cursor{0, 0, 0}, "anatøm", false}, *token)
 	// fmt.Printf("\x1b[90m%+v\x1b[0m\n", token)
 
 	token, err = scanner.Next()
 	t.AssertNoError(err)
-	t.AssertEqual(Token{OPERATOR, Cursor{0, 7}, "+"}, *token)
+	t.AssertEqual(Token{OPERATOR, Cursor{0, 7, 7}, "+", false}, *token)
 	// fmt.Printf("\x1b[90m%+v\x1b[0m\n", token)
 
 	token, err = scanner.Next()
 	t.AssertNoError(err)
-	t.AssertEqual(Token{NUMBER, Cursor{0, 9}, "1.20"}, *token)
+	t.AssertEqual(Token{NUMBER, Cursor{0, 9, 9}, "1.20", false}, *token)
 	// fmt.Printf("\x1b[90m%+v\x1b[0m\n", token)
 }
 
@@ -40,16 +41,16 @@ func TestMultilineWithComments(raw_t *testing.T) {
 
 	test_source := "can + /* it \nhandle */ // maybe\n{ \"this\" } \nasdf"
 	tokens := make([]Token, 10)
-	tokens[0] = Token{ATOM, Cursor{0, 0}, "can"}
-	tokens[1] = Token{OPERATOR, Cursor{0, 4}, "+"}
-	tokens[2] = Token{COMMENT, Cursor{0, 6}, "/* it \nhandle */"}
-	tokens[3] = Token{COMMENT, Cursor{1, 10}, "// maybe"}
-	tokens[4] = Token{NEWLINE, Cursor{1, 18}, "\n"}
-	tokens[5] = Token{DELIMITER, Cursor{2, 0}, "{"}
-	tokens[6] = Token{STRING, Cursor{2, 2}, "\"this\""}
-	tokens[7] = Token{DELIMITER, Cursor{2, 9}, "}"}
-	tokens[8] = Token{NEWLINE, Cursor{2, 11}, "\n"}
-	tokens[9] = Token{ATOM, Cursor{3, 0}, "asdf"}
+	tokens[0] = Token{ATOM, Cursor{0, 0, 0}, "can", false}
+	tokens[1] = Token{OPERATOR, Cursor{0, 4, 4}, "+", false}
+	tokens[2] = Token{COMMENT, Cursor{0, 6, 6}, "/* it \nhandle */", false}
+	tokens[3] = Token{COMMENT, Cursor{1, 10, 23}, "// maybe", false}
+	tokens[4] = Token{NEWLINE, Cursor{1, 18, 31}, "\n", false}
+	tokens[5] = Token{DELIMITER, Cursor{2, 0, 32}, "{", true}
+	tokens[6] = Token{STRING, Cursor{2, 2, 34}, "\"this\"", false}
+	tokens[7] = Token{DELIMITER, Cursor{2, 9, 41}, "}", false}
+	tokens[8] = Token{NEWLINE, Cursor{2, 11, 43}, "\n", false}
+	tokens[9] = Token{ATOM, Cursor{3, 0, 44}, "asdf", true}
 
 	// fmt.Printf("\x1b[96m-- expected tokens ----\n%v\n--------------\x1b[0m\n", tokens)
 	// fmt.Printf("\x1b[96m-- lexing ----\n%s\n--------------\x1b[0m\n", test_source)
@@ -80,10 +81,10 @@ func TestAgainstShapeObjects(raw_t *testing.T) {
 	}
 
 	tokens := make([]Token, 3)
-	tokens[0] = Token{COMMENT, Cursor{0, 0}, "// @src https://developer.mozilla.org/en-US/docs/Web/JavaScript/Reference/Global_Objects/Object/create"}
-	tokens[1] = Token{NEWLINE, Cursor{0, 102}, "\n"}
+	tokens[0] = Token{COMMENT, Cursor{0, 0, 0}, "// @src https://developer.mozilla.org/en-US/docs/Web/JavaScript/Reference/Global_Objects/Object/create", false}
+	tokens[1] = Token{NEWLINE, Cursor{0, 102, 102}, "\n", false}
 	// todo fix: don't emit newlines for blank lines
-	tokens[2] = Token{NEWLINE, Cursor{1, 0}, "\n"}
+	tokens[2] = Token{NEWLINE, Cursor{1, 0, 103}, "\n", false}
 
 	inputReader := bufio.NewReader(source)
 	scanner := NewTokenScanner(inputReader)
@@ -104,3 +105,404 @@ func TestAgainstShapeObjects(raw_t *testing.T) {
 	// token, _ := scanner.Next()
 	// t.Assert(token == nil, "scanner emitting excessive symbols")
 }
+
+func TestStringScanning(raw_t *testing.T) {
+	t := NewTestWrapper(raw_t)
+
+	test_source := "'single' \"dou\\\"ble\" 'it\\'s' \"con\\\ntinued\""
+	tokens := make([]Token, 4)
+	tokens[0] = Token{STRING, Cursor{0, 0, 0}, "'single'", false}
+	tokens[1] = Token{STRING, Cursor{0, 9, 9}, "\"dou\\\"ble\"", false}
+	tokens[2] = Token{STRING, Cursor{0, 20, 20}, "'it\\'s'", false}
+	tokens[3] = Token{STRING, Cursor{0, 28, 28}, "\"con\\\ntinued\"", false}
+
+	inputReader := strings.NewReader(test_source)
+	scanner := NewTokenScanner(inputReader)
+
+	for _, etkn := range tokens {
+		token, err := scanner.Next()
+		if !(t.AssertNoError(err) &&
+			t.Assert(token != nil, "unexpected end of scanner") &&
+			t.AssertEqual(etkn, *token)) {
+			return
+		}
+	}
+
+	token, _ := scanner.Next()
+	t.Assert(token == nil, "scanner emitting excessive symbols")
+}
+
+func TestStringScanningErrors(raw_t *testing.T) {
+	t := NewTestWrapper(raw_t)
+
+	expectations := map[string]SyntaxError{
+		"'abc":              {"unterminated string literal", Cursor{0, 0, 0}},
+		"x = \"ab\ncd\"":    {"unterminated string literal", Cursor{0, 7, 7}},
+		"\"\\x4G\"":         {"invalid hexadecimal escape sequence", Cursor{0, 1, 1}},
+		"\"ok\\u12\"":       {"invalid Unicode escape sequence", Cursor{0, 3, 3}},
+		"\"\\u{110000}\"":   {"undefined Unicode code-point", Cursor{0, 1, 1}},
+		"\"a\\\nb\\u{zz}\"": {"invalid Unicode escape sequence", Cursor{1, 1, 5}},
+	}
+
+	for source, expected := range expectations {
+		scanner := NewTokenScanner(strings.NewReader(source))
+		var err error
+		for err == nil {
+			var token *Token
+			token, err = scanner.Next()
+			if token == nil {
+				break
+			}
+		}
+		sntxErr, ok := err.(*SyntaxError)
+		if t.Assert(ok, "expected *SyntaxError for %q, got %#v", source, err) {
+			t.AssertEqual(expected, *sntxErr)
+		}
+	}
+}
+
+func TestNumberScanning(raw_t *testing.T) {
+	t := NewTestWrapper(raw_t)
+
+	test_source := "1e-9 .5 0xE+1 1_000n 1..a"
+	tokens := make([]Token, 9)
+	tokens[0] = Token{NUMBER, Cursor{0, 0, 0}, "1e-9", false}
+	tokens[1] = Token{NUMBER, Cursor{0, 5, 5}, ".5", false}
+	tokens[2] = Token{NUMBER, Cursor{0, 8, 8}, "0xE", false}
+	tokens[3] = Token{OPERATOR, Cursor{0, 11, 11}, "+", false}
+	tokens[4] = Token{NUMBER, Cursor{0, 12, 12}, "1", false}
+	tokens[5] = Token{NUMBER, Cursor{0, 14, 14}, "1_000n", false}
+	tokens[6] = Token{NUMBER, Cursor{0, 21, 21}, "1.", false}
+	tokens[7] = Token{OPERATOR, Cursor{0, 23, 23}, ".", false}
+	tokens[8] = Token{ATOM, Cursor{0, 24, 24}, "a", false}
+
+	inputReader := strings.NewReader(test_source)
+	scanner := NewTokenScanner(inputReader)
+
+	for _, etkn := range tokens {
+		token, err := scanner.Next()
+		if !(t.AssertNoError(err) &&
+			t.Assert(token != nil, "unexpected end of scanner") &&
+			t.AssertEqual(etkn, *token)) {
+			return
+		}
+	}
+
+	token, _ := scanner.Next()
+	t.Assert(token == nil, "scanner emitting excessive symbols")
+}
+
+func TestNumberScanningErrors(raw_t *testing.T) {
+	t := NewTestWrapper(raw_t)
+
+	expectations := map[string]string{
+		"0x":    "invalid numeric literal",
+		"1e+":   "invalid numeric literal",
+		"1__0":  "invalid numeric literal",
+		"1_":    "invalid numeric literal",
+		"0_1":   "invalid numeric literal",
+		"0b102": "invalid numeric literal",
+		"0o8":   "invalid numeric literal",
+		"1.5n":  "invalid BigInt literal",
+		"01n":   "invalid BigInt literal",
+	}
+
+	for source, message := range expectations {
+		scanner := NewTokenScanner(strings.NewReader("x = " + source))
+		var err error
+		for err == nil {
+			var token *Token
+			token, err = scanner.Next()
+			if token == nil {
+				break
+			}
+		}
+		sntxErr, ok := err.(*SyntaxError)
+		if t.Assert(ok, "expected *SyntaxError for %q, got %#v", source, err) {
+			t.AssertEqual(SyntaxError{message, Cursor{0, 4, 4}}, *sntxErr)
+		}
+	}
+}
+
+func TestRegexScanning(raw_t *testing.T) {
+	t := NewTestWrapper(raw_t)
+
+	test_source := "a / b; x = /[/]\\//g; if (x) /y/"
+	tokens := make([]Token, 13)
+	tokens[0] = Token{ATOM, Cursor{0, 0, 0}, "a", false}
+	tokens[1] = Token{OPERATOR, Cursor{0, 2, 2}, "/", false}
+	tokens[2] = Token{ATOM, Cursor{0, 4, 4}, "b", false}
+	tokens[3] = Token{DELIMITER, Cursor{0, 5, 5}, ";", false}
+	tokens[4] = Token{ATOM, Cursor{0, 7, 7}, "x", false}
+	tokens[5] = Token{OPERATOR, Cursor{0, 9, 9}, "=", false}
+	tokens[6] = Token{REGEX, Cursor{0, 11, 11}, "/[/]\\//g", false}
+	tokens[7] = Token{DELIMITER, Cursor{0, 19, 19}, ";", false}
+	tokens[8] = Token{ATOM, Cursor{0, 21, 21}, "if", false}
+	tokens[9] = Token{DELIMITER, Cursor{0, 24, 24}, "(", false}
+	tokens[10] = Token{ATOM, Cursor{0, 25, 25}, "x", false}
+	tokens[11] = Token{DELIMITER, Cursor{0, 26, 26}, ")", false}
+	tokens[12] = Token{REGEX, Cursor{0, 28, 28}, "/y/", false}
+
+	inputReader := strings.NewReader(test_source)
+	scanner := NewTokenScanner(inputReader)
+
+	for _, etkn := range tokens {
+		token, err := scanner.Next()
+		if !(t.AssertNoError(err) &&
+			t.Assert(token != nil, "unexpected end of scanner") &&
+			t.AssertEqual(etkn, *token)) {
+			return
+		}
+	}
+
+	token, _ := scanner.Next()
+	t.Assert(token == nil, "scanner emitting excessive symbols")
+}
+
+func TestRegexScanningErrors(raw_t *testing.T) {
+	t := NewTestWrapper(raw_t)
+
+	expectations := map[string]string{
+		"/abc":  "unterminated regular expression",
+		"/a[/]": "unterminated regular expression",
+		"/a\n/": "unterminated regular expression",
+		"/a/gg": "invalid regular expression flags",
+		"/a/x":  "invalid regular expression flags",
+		"/a/uv": "invalid regular expression flags",
+	}
+
+	for source, message := range expectations {
+		scanner := NewTokenScanner(strings.NewReader("x = " + source))
+		var err error
+		for err == nil {
+			var token *Token
+			token, err = scanner.Next()
+			if token == nil {
+				break
+			}
+		}
+		sntxErr, ok := err.(*SyntaxError)
+		if t.Assert(ok, "expected *SyntaxError for %q, got %#v", source, err) {
+			t.AssertEqual(message, sntxErr.Message)
+		}
+	}
+}
+
+func TestTemplateScanning(raw_t *testing.T) {
+	t := NewTestWrapper(raw_t)
+
+	test_source := "`a${ {b: `c`} }d${e}` / 2"
+	tokens := make([]Token, 11)
+	tokens[0] = Token{TEMPLATE, Cursor{0, 0, 0}, "`a${", false}
+	tokens[1] = Token{DELIMITER, Cursor{0, 5, 5}, "{", false}
+	tokens[2] = Token{ATOM, Cursor{0, 6, 6}, "b", false}
+	tokens[3] = Token{OPERATOR, Cursor{0, 7, 7}, ":", false}
+	tokens[4] = Token{TEMPLATE, Cursor{0, 9, 9}, "`c`", false}
+	tokens[5] = Token{DELIMITER, Cursor{0, 12, 12}, "}", false}
+	tokens[6] = Token{TEMPLATE, Cursor{0, 14, 14}, "}d${", false}
+	tokens[7] = Token{ATOM, Cursor{0, 18, 18}, "e", false}
+	tokens[8] = Token{TEMPLATE, Cursor{0, 19, 19}, "}`", false}
+	tokens[9] = Token{OPERATOR, Cursor{0, 22, 22}, "/", false}
+	tokens[10] = Token{NUMBER, Cursor{0, 24, 24}, "2", false}
+
+	inputReader := strings.NewReader(test_source)
+	scanner := NewTokenScanner(inputReader)
+
+	for _, etkn := range tokens {
+		token, err := scanner.Next()
+		if !(t.AssertNoError(err) &&
+			t.Assert(token != nil, "unexpected end of scanner") &&
+			t.AssertEqual(etkn, *token)) {
+			return
+		}
+	}
+
+	token, _ := scanner.Next()
+	t.Assert(token == nil, "scanner emitting excessive symbols")
+}
+
+func TestPrivateNameScanning(raw_t *testing.T) {
+	t := NewTestWrapper(raw_t)
+
+	test_source := "this.#a1 / 2"
+	tokens := make([]Token, 5)
+	tokens[0] = Token{ATOM, Cursor{0, 0, 0}, "this", false}
+	tokens[1] = Token{OPERATOR, Cursor{0, 4, 4}, ".", false}
+	tokens[2] = Token{PRIVATE_NAME, Cursor{0, 5, 5}, "#a1", false}
+	tokens[3] = Token{OPERATOR, Cursor{0, 9, 9}, "/", false}
+	tokens[4] = Token{NUMBER, Cursor{0, 11, 11}, "2", false}
+
+	inputReader := strings.NewReader(test_source)
+	scanner := NewTokenScanner(inputReader)
+
+	for _, etkn := range tokens {
+		token, err := scanner.Next()
+		if !(t.AssertNoError(err) &&
+			t.Assert(token != nil, "unexpected end of scanner") &&
+			t.AssertEqual(etkn, *token)) {
+			return
+		}
+	}
+
+	token, _ := scanner.Next()
+	t.Assert(token == nil, "scanner emitting excessive symbols")
+
+	for _, source := range []string{"#", "# a", "#1"} {
+		_, err := NewTokenScanner(strings.NewReader(source)).Next()
+		sntxErr, ok := err.(*SyntaxError)
+		if t.Assert(ok, "expected *SyntaxError for %q, got %#v", source, err) {
+			t.AssertEqual("invalid private name", sntxErr.Message)
+		}
+	}
+}
+
+func TestPunctuatorScanning(raw_t *testing.T) {
+	t := NewTestWrapper(raw_t)
+
+	expectations := map[string][]string{
+		"a=-1":     {"a", "=", "-", "1"},
+		"x+-y":     {"x", "+", "-", "y"},
+		"!!a":      {"!", "!", "a"},
+		"a?.5:1":   {"a", "?", ".5", ":", "1"},
+		"a?.b":     {"a", "?.", "b"},
+		"a??=b":    {"a", "??=", "b"},
+		"(...a)=>": {"(", "...", "a", ")", "=>"},
+		"a>>>=b":   {"a", ">>>=", "b"},
+		"a>>>>b":   {"a", ">>>", ">", "b"},
+		"a===!b":   {"a", "===", "!", "b"},
+		"{a:-1}":   {"{", "a", ":", "-", "1", "}"},
+		"a**-b":    {"a", "**", "-", "b"},
+		"f().b":    {"f", "(", ")", ".", "b"},
+	}
+	for source, values := range expectations {
+		scanner := NewTokenScanner(strings.NewReader(source))
+		scanned := []string{}
+		for {
+			token, err := scanner.Next()
+			if !t.AssertNoError(err) || token == nil {
+				break
+			}
+			scanned = append(scanned, token.Value)
+		}
+		t.AssertEqual(values, scanned)
+	}
+}
+
+func TestTokenize(raw_t *testing.T) {
+	t := NewTestWrapper(raw_t)
+
+	tokens, err := Tokenize("if (a) x = /b/g; // c\nclass A { #d = null }", TokenizeOptions{Comment: true})
+	if !t.AssertNoError(err) {
+		return
+	}
+	types := []string{}
+	values := []string{}
+	for _, token := range tokens {
+		types = append(types, token.Type)
+		values = append(values, token.Value)
+	}
+	t.AssertEqual([]string{"Keyword", "Punctuator", "Identifier", "Punctuator", "Identifier", "Punctuator",
+		"RegularExpression", "Punctuator", "LineComment", "Keyword", "Identifier", "Punctuator",
+		"PrivateIdentifier", "Punctuator", "Null", "Punctuator"}, types)
+	t.AssertEqual([]string{"if", "(", "a", ")", "x", "=", "/b/g", ";", " c", "class", "A", "{",
+		"#d", "=", "null", "}"}, values)
+	t.AssertEqual(RegExp{"b", "g"}, *tokens[6].Regex)
+
+	tokens, err = Tokenize("x = `a${true}b`; // c", TokenizeOptions{})
+	if !t.AssertNoError(err) {
+		return
+	}
+	encoded, err := json.Marshal(tokens)
+	t.AssertNoError(err)
+	t.AssertEqual(`[{"type":"Identifier","value":"x"},{"type":"Punctuator","value":"="},`+
+		`{"type":"Template","value":"`+"`"+`a${"},{"type":"Boolean","value":"true"},`+
+		`{"type":"Template","value":"}b`+"`"+`"},{"type":"Punctuator","value":";"}]`, string(encoded))
+}
+
+func TestTokenizeDivisionAfterKeywordNames(raw_t *testing.T) {
+	t := NewTestWrapper(raw_t)
+
+	expectations := map[string][]string{
+		"a.in / b / c":           {"Identifier", "Punctuator", "Keyword", "Punctuator", "Identifier", "Punctuator", "Identifier"},
+		"p?.new / 2 / 1":         {"Identifier", "Punctuator", "Keyword", "Punctuator", "Numeric", "Punctuator", "Numeric"},
+		"of / 2 / 1":             {"Identifier", "Punctuator", "Numeric", "Punctuator", "Numeric"},
+		"return / 2 / 1":         {"Keyword", "RegularExpression", "Numeric"},
+		"x = function () {} / 2": {"Identifier", "Punctuator", "Keyword", "Punctuator", "Punctuator", "Punctuator", "Punctuator", "Punctuator", "Numeric"},
+		"x = class {} / 2":       {"Identifier", "Punctuator", "Keyword", "Punctuator", "Punctuator", "Punctuator", "Numeric"},
+		"function f() {} /a/g":   {"Keyword", "Identifier", "Punctuator", "Punctuator", "Punctuator", "Punctuator", "RegularExpression"},
+	}
+	for source, types := range expectations {
+		tokens, err := Tokenize(source, TokenizeOptions{})
+		if !t.AssertNoError(err) {
+			continue
+		}
+		scanned := []string{}
+		for _, token := range tokens {
+			scanned = append(scanned, token.Type)
+		}
+		t.AssertEqual(types, scanned)
+	}
+}
+
+func TestTokenizeContextualKeywords(raw_t *testing.T) {
+	t := NewTestWrapper(raw_t)
+
+	// of, yield and await are keywords before a regular expression only where they are operators
+	expectations := map[string]bool{
+		"function* g() { yield /a/g }":                   true,
+		"function f() { yield / 2 / 1 }":                 false,
+		"yield / 2 / 1":                                  false,
+		"x = { *g() { yield /a/g } }":                    true,
+		"for (x of /a/g);":                               true,
+		"for (var of of /a/g);":                          true,
+		"for (x = of / 2 / 1;;);":                        false,
+		"for await (x of /a/g);":                         true,
+		"async function f() { await /a/g }":              true,
+		"async x => await /a/g":                          true,
+		"class A { async m() { await /a/g } }":           true,
+		"function f() { await / 2 / 1 }":                 false,
+		"async function f() { () => { await / 2 / 1 } }": false,
+	}
+	for source, regex := range expectations {
+		tokens, err := Tokenize(source, TokenizeOptions{})
+		if !t.AssertNoError(err) {
+			continue
+		}
+		scanned := false
+		for _, token := range tokens {
+			scanned = scanned || token.Type == "RegularExpression"
+		}
+		t.Assert(regex == scanned, "expected regular expression %v in %q", regex, source)
+	}
+}
+
+func TestTokenizeLocations(raw_t *testing.T) {
+	t := NewTestWrapper(raw_t)
+
+	tokens, err := Tokenize("a\n  /* b */ 1", TokenizeOptions{Range: true, Loc: true, Comment: true})
+	if !t.AssertNoError(err) || !t.AssertEqual(3, len(tokens)) {
+		return
+	}
+	encoded, err := json.Marshal(tokens[1])
+	t.AssertNoError(err)
+	t.AssertEqual(`{"type":"BlockComment","value":" b ","range":[4,11],`+
+		`"loc":{"start":{"line":2,"column":2},"end":{"line":2,"column":9}}}`, string(encoded))
+	t.AssertEqual([2]int{12, 13}, *tokens[2].Range)
+}
+
+func TestTokenizeErrors(raw_t *testing.T) {
+	t := NewTestWrapper(raw_t)
+
+	expectations := map[string]string{
+		"a = 'b":    "unterminated string literal",
+		"/* a":      "incomplete multiline comment",
+		"x = /a/gg": "invalid regular expression flags",
+	}
+	for source, message := range expectations {
+		_, err := Tokenize(source, TokenizeOptions{})
+		perr, ok := err.(*ParseError)
+		if t.Assert(ok, "expected *ParseError for %q, got %#v", source, err) {
+			t.Assert(strings.Contains(perr.Message, message), "expected %q for %q, got %q", message, source, perr.Message)
+		}
+	}
+}
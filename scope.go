@@ -0,0 +1,182 @@
+package jaess
+
+// names declared in one scope, for reporting conflicting declarations
+type _Scope struct {
+	lexical   map[string]bool
+	vars      map[string]bool
+	functions map[string]bool
+	// function bodies and the program, where var declarations stop hoisting
+	function bool
+	// blocks and the top of a module, where function declarations are lexical
+	lexicalFunctions bool
+	// a catch parameter may be redeclared by var, but not by let or const
+	catchParam string
+}
+
+func _NewScope(function bool) *_Scope {
+	scope := new(_Scope)
+	scope.lexical = map[string]bool{}
+	scope.vars = map[string]bool{}
+	scope.functions = map[string]bool{}
+	scope.function = function
+	scope.lexicalFunctions = !function
+	return scope
+}
+
+// begins a block or function scope
+func (self *Parser) enterScope(function bool) *_Scope {
+	scope := _NewScope(function)
+	self.scopes = append(self.scopes, scope)
+	return scope
+}
+
+// ends the innermost scope
+func (self *Parser) leaveScope() {
+	self.scopes = self.scopes[:len(self.scopes)-1]
+}
+
+func (self *Parser) currentScope() *_Scope {
+	return self.scopes[len(self.scopes)-1]
+}
+
+// declares a let, const or class binding in the innermost scope
+func (self *Parser) declareLexical(name string, location Cursor) error {
+	if name == "let" {
+		return NewParseError("let is disallowed as a lexically bound name").SetLocation(location)
+	}
+	scope := self.currentScope()
+	if scope.lexical[name] || scope.vars[name] || scope.functions[name] {
+		return self.redeclarationError(name, location)
+	}
+	scope.lexical[name] = true
+	return nil
+}
+
+// declares a var binding, which hoists through blocks to the enclosing function
+func (self *Parser) declareVar(name string, location Cursor) error {
+	for i := len(self.scopes) - 1; i >= 0; i-- {
+		scope := self.scopes[i]
+		if (scope.lexical[name] && scope.catchParam != name) || (scope.functions[name] && scope.lexicalFunctions) {
+			return self.redeclarationError(name, location)
+		}
+		scope.vars[name] = true
+		if scope.function {
+			break
+		}
+	}
+	return nil
+}
+
+// declares a function declaration's name, which is lexical inside blocks
+// but behaves like var at the top of a function or script
+func (self *Parser) declareFunction(name string, location Cursor) error {
+	scope := self.currentScope()
+	if scope.lexical[name] || (scope.lexicalFunctions && scope.vars[name]) {
+		return self.redeclarationError(name, location)
+	}
+	// scripts may repeat a function in a block for older code, but modules may not
+	if scope.lexicalFunctions && scope.functions[name] && self.SourceType == "module" {
+		return self.redeclarationError(name, location)
+	}
+	scope.functions[name] = true
+	return nil
+}
+
+func (self *Parser) redeclarationError(name string, location Cursor) error {
+	return NewParseError("identifier '%s' has already been declared", name).SetLocation(location)
+}
+
+// private names of one class body, which may be used before they are declared
+type _Class struct {
+	// kinds of declared names, such as "method" or "static get"
+	privateNames map[string]string
+	// private name tokens referenced in the body
+	privateUses []*Token
+}
+
+// begins a class body
+func (self *Parser) enterClass() {
+	class := new(_Class)
+	class.privateNames = map[string]string{}
+	self.classes = append(self.classes, class)
+}
+
+// ends the innermost class body, passing names it does not declare to the enclosing class
+func (self *Parser) leaveClass() error {
+	class := self.classes[len(self.classes)-1]
+	self.classes = self.classes[:len(self.classes)-1]
+	for _, token := range class.privateUses {
+		if _, ok := class.privateNames[token.Value]; ok {
+			continue
+		}
+		err := self.usePrivateName(token)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// declares a private field, method or accessor in the innermost class body
+func (self *Parser) declarePrivateName(token *Token, kind string, static bool) error {
+	if token.Value == "#constructor" {
+		return NewParseError("classes may not have a private field named '#constructor'").SetLocation(token.Location)
+	}
+	prefix := ""
+	if static {
+		prefix = "static "
+	}
+	kind = prefix + kind
+
+	class := self.classes[len(self.classes)-1]
+	if previous, ok := class.privateNames[token.Value]; ok {
+		// a getter and setter may share a name
+		paired := (previous == prefix+"get" && kind == prefix+"set") ||
+			(previous == prefix+"set" && kind == prefix+"get")
+		if !paired {
+			return self.redeclarationError(token.Value, token.Location)
+		}
+		kind = prefix + "accessor"
+	}
+	class.privateNames[token.Value] = kind
+	return nil
+}
+
+// records a reference to a private name, checked once the class body ends
+func (self *Parser) usePrivateName(token *Token) error {
+	if len(self.classes) == 0 {
+		return NewParseError("private field '%s' must be declared in an enclosing class", token.Value).SetLocation(token.Location)
+	}
+	class := self.classes[len(self.classes)-1]
+	class.privateUses = append(class.privateUses, token)
+	return nil
+}
+
+// declares a name exported by the module
+func (self *Parser) declareExport(name string, location Cursor) error {
+	if self.exports == nil {
+		self.exports = map[string]bool{}
+	}
+	if self.exports[name] {
+		return NewParseError("duplicate export of '%s'", name).SetLocation(location)
+	}
+	self.exports[name] = true
+	return nil
+}
+
+// records a local binding named by an export list, which may be declared further down
+func (self *Parser) useExportLocal(token *Token) {
+	self.exportLocals = append(self.exportLocals, token)
+}
+
+// checks that the bindings named by export lists are declared at the top of the module
+func (self *Parser) checkExportLocals() error {
+	scope := self.scopes[0]
+	for _, token := range self.exportLocals {
+		name := token.Value
+		if !scope.lexical[name] && !scope.vars[name] && !scope.functions[name] {
+			return NewParseError("export '%s' is not defined in module", name).SetLocation(token.Location)
+		}
+	}
+	return nil
+}
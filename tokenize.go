@@ -0,0 +1,100 @@
+package jaess
+
+import (
+	"strings"
+)
+
+// a token as esprima.tokenize reports it, such as a Keyword or Punctuator
+type StandardToken struct {
+	Type  string          `json:"type"`
+	Value string          `json:"value"`
+	Regex *RegExp         `json:"regex,omitempty"`
+	Range *[2]int         `json:"range,omitempty"`
+	Loc   *SourceLocation `json:"loc,omitempty"`
+}
+
+// what Tokenize reports besides the type and value of each token
+type TokenizeOptions struct {
+	// attach range, with start and end offsets, to every token
+	Range bool
+	// attach loc, with start and end lines and columns, to every token
+	Loc bool
+	// include comments, as LineComment and BlockComment tokens
+	Comment bool
+}
+
+// splits source into the tokens esprima.tokenize would report
+func Tokenize(source string, options TokenizeOptions) ([]*StandardToken, error) {
+	// the parser is only used for reporting errors from its scanner
+	parser := NewParser(strings.NewReader(source))
+	tokens := []*StandardToken{}
+	for {
+		token, err := parser.scanner.Next()
+		if err != nil {
+			return nil, parser.scannerError(err)
+		}
+		if token == nil {
+			return tokens, nil
+		}
+		if token.Type == NEWLINE || (token.Type == COMMENT && !options.Comment) {
+			continue
+		}
+
+		standard := new(StandardToken)
+		standard.Type = _StandardTokenType(token)
+		standard.Value = token.Value
+		switch token.Type {
+		case COMMENT:
+			_, standard.Value = _CommentText(token.Value)
+		case REGEX:
+			pattern, flags, sntxErr := DecodeRegexLiteral(token.Value, token.Location)
+			if sntxErr != nil {
+				return nil, parser.scannerError(sntxErr)
+			}
+			standard.Regex = &RegExp{pattern, flags}
+		}
+
+		end := token.End()
+		if options.Range {
+			standard.Range = &[2]int{token.Location.Offset, end.Offset}
+		}
+		if options.Loc {
+			standard.Loc = &SourceLocation{token.Location.Position(), end.Position()}
+		}
+		tokens = append(tokens, standard)
+	}
+}
+
+// the esprima.tokenize type of a scanned token
+func _StandardTokenType(token *Token) string {
+	switch token.Type {
+	case DELIMITER, OPERATOR:
+		return "Punctuator"
+	case NUMBER:
+		return "Numeric"
+	case STRING:
+		return "String"
+	case REGEX:
+		return "RegularExpression"
+	case TEMPLATE:
+		return "Template"
+	case PRIVATE_NAME:
+		return "PrivateIdentifier"
+	case COMMENT:
+		if strings.HasPrefix(token.Value, "/*") {
+			return "BlockComment"
+		}
+		return "LineComment"
+	}
+
+	switch token.Value {
+	case "null":
+		return "Null"
+	case "true", "false":
+		return "Boolean"
+	}
+	if IsKeyword(token.Value) {
+		return "Keyword"
+	}
+	return "Identifier"
+}
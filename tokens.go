@@ -9,6 +9,20 @@ type Token struct {
 	Type     TokenType
 	Location Cursor
 	Value    string
+	// a line terminator comes between this and the previous token,
+	// other than comments and newlines, which automatic semicolon insertion depends on
+	NewlineBefore bool
+}
+
+// location just past the end of the token
+func (self *Token) End() Cursor {
+	end := self.Location
+	var prev rune
+	for _, r := range self.Value {
+		end._IncrementAfterRune(prev, r)
+		prev = r
+	}
+	return end
 }
 
 type TokenType int
@@ -22,6 +36,9 @@ const (
 	STRING
 	COMMENT
 	NEWLINE
+	REGEX
+	TEMPLATE
+	PRIVATE_NAME
 
 	_HIDDEN
 	_SPACE
@@ -31,6 +48,16 @@ const (
 	_COMMENT_MULTI_LINE_MAY_END
 	_STRING_SINGLE_QUOTE
 	_STRING_DOUBLE_QUOTE
+	_STRING_SINGLE_QUOTE_ESCAPE
+	_STRING_DOUBLE_QUOTE_ESCAPE
+	_REGEX
+	_REGEX_ESCAPE
+	_REGEX_CLASS
+	_REGEX_CLASS_ESCAPE
+	_TEMPLATE
+	_TEMPLATE_ESCAPE
+	_TEMPLATE_DOLLAR
+	_HASH
 )
 
 func (self TokenType) String() string {
@@ -52,6 +79,12 @@ func (self TokenType) String() string {
 		return "COMMENT"
 	case NEWLINE:
 		return "NEWLINE"
+	case REGEX:
+		return "REGEX"
+	case TEMPLATE:
+		return "TEMPLATE"
+	case PRIVATE_NAME:
+		return "PRIVATE_NAME"
 
 	case _SPACE:
 		return "_SPACE"
@@ -67,6 +100,26 @@ func (self TokenType) String() string {
 		return "_STRING_SINGLE_QUOTE"
 	case _STRING_DOUBLE_QUOTE:
 		return "_STRING_DOUBLE_QUOTE"
+	case _STRING_SINGLE_QUOTE_ESCAPE:
+		return "_STRING_SINGLE_QUOTE_ESCAPE"
+	case _STRING_DOUBLE_QUOTE_ESCAPE:
+		return "_STRING_DOUBLE_QUOTE_ESCAPE"
+	case _REGEX:
+		return "_REGEX"
+	case _REGEX_ESCAPE:
+		return "_REGEX_ESCAPE"
+	case _REGEX_CLASS:
+		return "_REGEX_CLASS"
+	case _REGEX_CLASS_ESCAPE:
+		return "_REGEX_CLASS_ESCAPE"
+	case _TEMPLATE:
+		return "_TEMPLATE"
+	case _TEMPLATE_ESCAPE:
+		return "_TEMPLATE_ESCAPE"
+	case _TEMPLATE_DOLLAR:
+		return "_TEMPLATE_DOLLAR"
+	case _HASH:
+		return "_HASH"
 
 	}
 	return "<#error: bad value>"
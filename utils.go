@@ -3,11 +3,21 @@ package jaess
 import (
   "unicode"
   "regexp"
+  "strings"
 )
 
-// spaces excluding newlines
+// spaces excluding line terminators
 func IsInlineWhitespaceRune(r rune) bool {
-  return unicode.IsSpace(r) && r != '\n'
+  return unicode.IsSpace(r) && !IsLineTerminatorRune(r)
+}
+
+// line terminators end single line comments, strings and regular expressions
+func IsLineTerminatorRune(r rune) bool {
+  switch r {
+  case '\n', '\r', '\u2028', '\u2029':
+    return true
+  }
+  return false
 }
 
 // delimiter: punct breaks up the token
@@ -21,7 +31,7 @@ func IsDelimeterRune(r rune) bool {
 }
 
 // pretty much punct thats not a delimeter
-// contiguous operator runes emit one token
+// contiguous operator runes emit the longest punctuator they spell
 func IsOperatorRune(r rune) bool {
   switch r {
   case '<', '>', '+', '-', '*', '/', '%', '=', '&', '|', '^', '!', '~', '?', ':', '.':
@@ -30,6 +40,26 @@ func IsOperatorRune(r rune) bool {
   return false
 }
 
+// punctuators made of operator runes
+var _Punctuators = map[string]bool{
+  ".": true, "...": true, "?": true, "?.": true, ":": true, "=>": true,
+  "<": true, ">": true, "<=": true, ">=": true,
+  "==": true, "!=": true, "===": true, "!==": true,
+  "+": true, "-": true, "*": true, "/": true, "%": true, "**": true,
+  "++": true, "--": true, "<<": true, ">>": true, ">>>": true,
+  "&": true, "|": true, "^": true, "!": true, "~": true,
+  "&&": true, "||": true, "??": true,
+  "=": true, "+=": true, "-=": true, "*=": true, "/=": true, "%=": true, "**=": true,
+  "<<=": true, ">>=": true, ">>>=": true, "&=": true, "|=": true, "^=": true,
+  "&&=": true, "||=": true, "??=": true,
+}
+
+// operator runes that may still grow into a punctuator
+// ".." is not one, but may become "..."
+func IsPunctuatorPrefix(value string) bool {
+  return _Punctuators[value] || value == ".."
+}
+
 // unicode digits only
 func IsDigitRune(r rune) bool {
   if unicode.IsDigit(r) {
@@ -38,6 +68,29 @@ func IsDigitRune(r rune) bool {
   return false
 }
 
+// hexadecimal digits, ascii only
+func IsHexDigitRune(r rune) bool {
+  return (r >= '0' && r <= '9') || (r >= 'a' && r <= 'f') || (r >= 'A' && r <= 'F')
+}
+
+// a decimal point may follow plain decimal digits, but not a legacy octal literal
+func _NumberMayContinueWithDot(value string) bool {
+  for _, r := range value {
+    if !(IsDigitRune(r) || r == '_') {
+      return false
+    }
+  }
+  return !legacyOctalRegexp.MatchString(value)
+}
+
+// an exponent sign may follow the e of a decimal literal, but not a hex digit
+func _NumberMayContinueWithSign(value string) bool {
+  if !strings.HasSuffix(value, "e") && !strings.HasSuffix(value, "E") {
+    return false
+  }
+  return !prefixedIntegerRegexp.MatchString(value)
+}
+
 // runes for keywords or identifiers
 func IsAtomRune(r rune) bool {
   if r == '_' || r == '$' || unicode.IsLetter(r) {
@@ -48,8 +101,123 @@ func IsAtomRune(r rune) bool {
 
 // unary operator token
 func IsUnaryOperator(token *Token) bool {
+  switch token.Type {
+  case OPERATOR:
+    switch token.Value {
+    case "-", "+", "!", "~":
+      return true
+    }
+  case ATOM:
+    switch token.Value {
+    case "typeof", "void", "delete":
+      return true
+    }
+  }
+  return false
+}
+
+// prefix or postfix update operator token
+func IsUpdateOperator(token *Token) bool {
+  return token.Type == OPERATOR && (token.Value == "++" || token.Value == "--")
+}
+
+// assignment operator token
+func IsAssignmentOperator(token *Token) bool {
+  if token.Type != OPERATOR {
+    return false
+  }
   switch token.Value {
-  case "-", "++", "--":
+  case "=", "+=", "-=", "*=", "/=", "%=", "**=", "<<=", ">>=", ">>>=",
+    "&=", "|=", "^=", "&&=", "||=", "??=":
+    return true
+  }
+  return false
+}
+
+// binding power of a binary operator token, higher binds tighter
+// returns 0 if the token is not a binary operator
+func BinaryPrecedence(token *Token) int {
+  switch token.Type {
+  case OPERATOR:
+    switch token.Value {
+    case "??", "||":
+      return 1
+    case "&&":
+      return 2
+    case "|":
+      return 3
+    case "^":
+      return 4
+    case "&":
+      return 5
+    case "==", "!=", "===", "!==":
+      return 6
+    case "<", ">", "<=", ">=":
+      return 7
+    case "<<", ">>", ">>>":
+      return 8
+    case "+", "-":
+      return 9
+    case "*", "/", "%":
+      return 10
+    case "**":
+      return 11
+    }
+  case ATOM:
+    switch token.Value {
+    case "instanceof", "in":
+      return 7
+    }
+  }
+  return 0
+}
+
+// binary operators that group from the right
+func IsRightAssociative(token *Token) bool {
+  return token.Type == OPERATOR && token.Value == "**"
+}
+
+// binary operators emitted as LogicalExpression
+func IsLogicalOperator(token *Token) bool {
+  if token.Type != OPERATOR {
+    return false
+  }
+  switch token.Value {
+  case "&&", "||", "??":
+    return true
+  }
+  return false
+}
+
+// reserved words reported as keywords when tokenizing, as by esprima.tokenize
+func IsKeyword(value string) bool {
+  switch value {
+  case "if", "in", "do", "var", "for", "new", "try", "let",
+    "this", "else", "case", "void", "with", "enum",
+    "while", "break", "catch", "throw", "const", "yield", "class", "super",
+    "return", "typeof", "delete", "switch", "export", "import",
+    "default", "finally", "extends", "function", "continue", "debugger", "instanceof":
+    return true
+  }
+  return false
+}
+
+// keywords after which a parenthesized condition appears, as in if (...) /re/
+func IsKeywordBeforeCondition(value string) bool {
+  switch value {
+  case "if", "while", "for", "with":
+    return true
+  }
+  return false
+}
+
+// keywords after which an expression is expected, rather than an operator
+// contextual keywords such as of, yield and await may be identifiers, so are left out
+// for the scanner to decide from where they appear
+func IsKeywordBeforeExpression(value string) bool {
+  switch value {
+  case "return", "typeof", "instanceof", "in", "new", "delete", "void",
+    "throw", "case", "do", "else", "extends":
     return true
   }
   return false